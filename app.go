@@ -8,7 +8,6 @@ import (
 	"os"
 
 	"path/filepath"
-	"regexp"
 
 	"spotiflac/backend"
 	"strings"
@@ -17,29 +16,44 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
-var isrcRegex = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}\d{2}\d{5}$`)
-
-func isValidISRC(isrc string) bool {
-	return isrcRegex.MatchString(isrc)
-}
-
 type App struct {
-	ctx context.Context
+	ctx       context.Context
+	scheduler *backend.DownloadScheduler
 }
 
 func NewApp() *App {
-	return &App{}
+	return &App{
+		scheduler: backend.NewDownloadScheduler(0),
+	}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 
+	backend.SubscribeDownloadEvents(func(event backend.DownloadEvent) {
+		runtime.EventsEmit(a.ctx, event.Name, event.Payload)
+	})
+
 	if err := backend.InitHistoryDB("SpotiFLAC"); err != nil {
 		fmt.Printf("Failed to init history DB: %v\n", err)
 	}
+
+	if pending, err := backend.GetPersistedDownloads(); err == nil && len(pending) > 0 {
+		fmt.Printf("Found %d unfinished downloads from previous session\n", len(pending))
+	}
+
+	go func() {
+		if _, err := backend.ScanLibrary(false); err != nil {
+			fmt.Printf("Failed to rescan library: %v\n", err)
+		}
+		if err := backend.StartLibraryWatcher(); err != nil {
+			fmt.Printf("Failed to start library watcher: %v\n", err)
+		}
+	}()
 }
 
 func (a *App) shutdown(ctx context.Context) {
+	backend.StopLibraryWatcher()
 	backend.CloseHistoryDB()
 }
 
@@ -51,43 +65,51 @@ type SpotifyMetadataRequest struct {
 }
 
 type DownloadRequest struct {
-	ISRC                 string `json:"isrc"`
-	Service              string `json:"service"`
-	Query                string `json:"query,omitempty"`
-	TrackName            string `json:"track_name,omitempty"`
-	ArtistName           string `json:"artist_name,omitempty"`
-	AlbumName            string `json:"album_name,omitempty"`
-	AlbumArtist          string `json:"album_artist,omitempty"`
-	ReleaseDate          string `json:"release_date,omitempty"`
-	CoverURL             string `json:"cover_url,omitempty"`
-	ApiURL               string `json:"api_url,omitempty"`
-	OutputDir            string `json:"output_dir,omitempty"`
-	AudioFormat          string `json:"audio_format,omitempty"`
-	FilenameFormat       string `json:"filename_format,omitempty"`
-	TrackNumber          bool   `json:"track_number,omitempty"`
-	Position             int    `json:"position,omitempty"`
-	UseAlbumTrackNumber  bool   `json:"use_album_track_number,omitempty"`
-	SpotifyID            string `json:"spotify_id,omitempty"`
-	EmbedLyrics          bool   `json:"embed_lyrics,omitempty"`
-	EmbedMaxQualityCover bool   `json:"embed_max_quality_cover,omitempty"`
-	ServiceURL           string `json:"service_url,omitempty"`
-	Duration             int    `json:"duration,omitempty"`
-	ItemID               string `json:"item_id,omitempty"`
-	SpotifyTrackNumber   int    `json:"spotify_track_number,omitempty"`
-	SpotifyDiscNumber    int    `json:"spotify_disc_number,omitempty"`
-	SpotifyTotalTracks   int    `json:"spotify_total_tracks,omitempty"`
-	SpotifyTotalDiscs    int    `json:"spotify_total_discs,omitempty"`
-	Copyright            string `json:"copyright,omitempty"`
-	Publisher            string `json:"publisher,omitempty"`
+	ISRC                 string                 `json:"isrc"`
+	Service              string                 `json:"service"`
+	Query                string                 `json:"query,omitempty"`
+	TrackName            string                 `json:"track_name,omitempty"`
+	ArtistName           string                 `json:"artist_name,omitempty"`
+	AlbumName            string                 `json:"album_name,omitempty"`
+	AlbumArtist          string                 `json:"album_artist,omitempty"`
+	ReleaseDate          string                 `json:"release_date,omitempty"`
+	CoverURL             string                 `json:"cover_url,omitempty"`
+	ApiURL               string                 `json:"api_url,omitempty"`
+	OutputDir            string                 `json:"output_dir,omitempty"`
+	AudioFormat          string                 `json:"audio_format,omitempty"`
+	FilenameFormat       string                 `json:"filename_format,omitempty"`
+	TrackNumber          bool                   `json:"track_number,omitempty"`
+	Position             int                    `json:"position,omitempty"`
+	UseAlbumTrackNumber  bool                   `json:"use_album_track_number,omitempty"`
+	SpotifyID            string                 `json:"spotify_id,omitempty"`
+	EmbedLyrics          bool                   `json:"embed_lyrics,omitempty"`
+	EmbedMaxQualityCover bool                   `json:"embed_max_quality_cover,omitempty"`
+	ServiceURL           string                 `json:"service_url,omitempty"`
+	Duration             int                    `json:"duration,omitempty"`
+	ItemID               string                 `json:"item_id,omitempty"`
+	SpotifyTrackNumber   int                    `json:"spotify_track_number,omitempty"`
+	SpotifyDiscNumber    int                    `json:"spotify_disc_number,omitempty"`
+	SpotifyTotalTracks   int                    `json:"spotify_total_tracks,omitempty"`
+	SpotifyTotalDiscs    int                    `json:"spotify_total_discs,omitempty"`
+	Copyright            string                 `json:"copyright,omitempty"`
+	Publisher            string                 `json:"publisher,omitempty"`
+	FallbackChain        []backend.FallbackStep `json:"fallback_chain,omitempty"`
+	ReplayGain           bool                   `json:"replay_gain,omitempty"`
+	LyricsSidecar        bool                   `json:"lyrics_sidecar,omitempty"`
+	LyricsFormat         string                 `json:"lyrics_format,omitempty"`
 }
 
 type DownloadResponse struct {
-	Success       bool   `json:"success"`
-	Message       string `json:"message"`
-	File          string `json:"file,omitempty"`
-	Error         string `json:"error,omitempty"`
-	AlreadyExists bool   `json:"already_exists,omitempty"`
-	ItemID        string `json:"item_id,omitempty"`
+	Success          bool                        `json:"success"`
+	Message          string                      `json:"message"`
+	File             string                      `json:"file,omitempty"`
+	Error            string                      `json:"error,omitempty"`
+	AlreadyExists    bool                        `json:"already_exists,omitempty"`
+	ItemID           string                      `json:"item_id,omitempty"`
+	Provider         string                      `json:"provider,omitempty"`
+	Quality          string                      `json:"quality,omitempty"`
+	SkippedProviders []backend.SkippedProvider   `json:"skipped_providers,omitempty"`
+	Verification     *backend.VerificationResult `json:"verification,omitempty"`
 }
 
 func (a *App) GetStreamingURLs(spotifyTrackID string) (string, error) {
@@ -186,13 +208,6 @@ func (a *App) SearchSpotifyByType(req SpotifySearchByTypeRequest) ([]backend.Sea
 
 func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 
-	if req.Service == "qobuz" && req.ISRC == "" && req.SpotifyID == "" {
-		return DownloadResponse{
-			Success: false,
-			Error:   "Spotify ID is required for Qobuz",
-		}, fmt.Errorf("spotify ID is required for Qobuz")
-	}
-
 	if req.Service == "" {
 		req.Service = "tidal"
 	}
@@ -208,7 +223,6 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		req.AudioFormat = "LOSSLESS"
 	}
 
-	var err error
 	var filename string
 
 	if req.FilenameFormat == "" {
@@ -227,9 +241,12 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		backend.AddToQueue(itemID, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID)
 	}
 
-	backend.SetDownloading(true)
+	req.ItemID = itemID
+	if err := backend.PersistDownloadRequest(itemID, req.TrackName, req.ArtistName, req.AlbumName, req); err != nil {
+		fmt.Printf("Warning: Failed to persist download request: %v\n", err)
+	}
+
 	backend.StartDownloadItem(itemID)
-	defer backend.SetDownloading(false)
 
 	spotifyURL := ""
 	if req.SpotifyID != "" {
@@ -280,8 +297,36 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 	}
 
+	trackReq := backend.TrackRequest{
+		OutputDir:            req.OutputDir,
+		Quality:              req.AudioFormat,
+		FilenameFormat:       req.FilenameFormat,
+		IncludeTrackNumber:   req.TrackNumber,
+		Position:             req.Position,
+		UseAlbumTrackNumber:  req.UseAlbumTrackNumber,
+		CoverURL:             req.CoverURL,
+		EmbedMaxQualityCover: req.EmbedMaxQualityCover,
+		ItemID:               itemID,
+		Tags: backend.TrackTags{
+			Title:       req.TrackName,
+			Artist:      req.ArtistName,
+			Album:       req.AlbumName,
+			AlbumArtist: req.AlbumArtist,
+			ReleaseDate: req.ReleaseDate,
+			TrackNumber: req.SpotifyTrackNumber,
+			DiscNumber:  req.SpotifyDiscNumber,
+			TotalTracks: req.SpotifyTotalTracks,
+			TotalDiscs:  req.SpotifyTotalDiscs,
+			Copyright:   req.Copyright,
+			Publisher:   req.Publisher,
+			SpotifyURL:  spotifyURL,
+			SpotifyID:   req.SpotifyID,
+			ISRC:        req.ISRC,
+		},
+	}
+
 	if req.TrackName != "" && req.ArtistName != "" {
-		expectedFilename := backend.BuildExpectedFilename(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.FilenameFormat, req.TrackNumber, req.Position, req.SpotifyDiscNumber, req.UseAlbumTrackNumber)
+		expectedFilename := backend.BuildExpectedFilename(trackReq)
 		expectedPath := filepath.Join(req.OutputDir, expectedFilename)
 
 		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 {
@@ -297,121 +342,51 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		}
 	}
 
-	switch req.Service {
-	case "amazon":
-		downloader := backend.NewAmazonDownloader()
-		if req.ServiceURL != "" {
-
-			filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-		} else {
-			if req.SpotifyID == "" {
-				return DownloadResponse{
-					Success: false,
-					Error:   "Spotify ID is required for Amazon Music",
-				}, fmt.Errorf("spotify ID is required for Amazon Music")
-			}
-			filename, err = downloader.DownloadBySpotifyID(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.CoverURL, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.EmbedMaxQualityCover, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-		}
-
-	case "tidal":
-		if req.ApiURL == "" || req.ApiURL == "auto" {
-			downloader := backend.NewTidalDownloader("")
-			if req.ServiceURL != "" {
-
-				filename, err = downloader.DownloadByURLWithFallback(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-			} else {
-				if req.SpotifyID == "" {
-					return DownloadResponse{
-						Success: false,
-						Error:   "Spotify ID is required for Tidal",
-					}, fmt.Errorf("spotify ID is required for Tidal")
-				}
-
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-			}
-		} else {
-			downloader := backend.NewTidalDownloader(req.ApiURL)
-			if req.ServiceURL != "" {
-
-				filename, err = downloader.DownloadByURL(req.ServiceURL, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-			} else {
-				if req.SpotifyID == "" {
-					return DownloadResponse{
-						Success: false,
-						Error:   "Spotify ID is required for Tidal",
-					}, fmt.Errorf("spotify ID is required for Tidal")
-				}
-
-				filename, err = downloader.Download(req.SpotifyID, req.OutputDir, req.AudioFormat, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
-			}
-		}
-
-	case "qobuz":
-		downloader := backend.NewQobuzDownloader()
-
-		quality := req.AudioFormat
-		if quality == "" {
-			quality = "6"
-		}
-
-		deezerISRC := req.ISRC
-
-		if len(deezerISRC) != 12 || !isValidISRC(deezerISRC) {
-			deezerISRC = ""
-		}
-
-		if deezerISRC == "" && req.SpotifyID != "" {
+	if match, err := backend.FindLibraryTrack(backend.LibraryQuery{
+		SpotifyID: req.SpotifyID,
+		ISRC:      req.ISRC,
+		Title:     req.TrackName,
+		Artist:    req.ArtistName,
+		Duration:  req.Duration,
+	}); err == nil && match != nil {
+		fmt.Printf("Found in library (%s): %s\n", match.MatchedBy, match.Track.Path)
+		backend.SkipDownloadItem(itemID, match.Track.Path)
+		return DownloadResponse{
+			Success:       true,
+			Message:       "File already exists in library",
+			File:          match.Track.Path,
+			AlreadyExists: true,
+			ItemID:        itemID,
+		}, nil
+	}
 
-			songlinkClient := backend.NewSongLinkClient()
-			deezerURL, err := songlinkClient.GetDeezerURLFromSpotify(req.SpotifyID)
-			if err != nil {
-				return DownloadResponse{
-					Success: false,
-					Error:   fmt.Sprintf("Failed to get Deezer URL: %v", err),
-				}, err
-			}
-			deezerISRC, err = backend.GetDeezerISRC(deezerURL)
-			if err != nil {
-				return DownloadResponse{
-					Success: false,
-					Error:   fmt.Sprintf("Failed to get ISRC from Deezer: %v", err),
-				}, err
-			}
-		}
-		if deezerISRC == "" {
-			return DownloadResponse{
-				Success: false,
-				Error:   "ISRC is required for Qobuz (could not fetch from Deezer)",
-			}, fmt.Errorf("ISRC is required for Qobuz")
+	chain := req.FallbackChain
+	if len(chain) == 0 {
+		chain = []backend.FallbackStep{{Service: req.Service, Quality: req.AudioFormat}}
+	}
+	for i := range chain {
+		if chain[i].Service == req.Service && chain[i].ServiceURL == "" {
+			chain[i].ServiceURL = req.ServiceURL
 		}
-		filename, err = downloader.DownloadByISRC(deezerISRC, req.OutputDir, quality, req.FilenameFormat, req.TrackNumber, req.Position, req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, req.UseAlbumTrackNumber, req.CoverURL, req.EmbedMaxQualityCover, req.SpotifyTrackNumber, req.SpotifyDiscNumber, req.SpotifyTotalTracks, req.SpotifyTotalDiscs, req.Copyright, req.Publisher, spotifyURL)
+	}
 
-	default:
-		return DownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Unknown service: %s", req.Service),
-		}, fmt.Errorf("unknown service: %s", req.Service)
+	providerReq := backend.ProviderRequest{
+		ApiURL:          req.ApiURL,
+		AcquireProvider: a.scheduler.AcquireProvider,
+		TrackRequest:    trackReq,
 	}
 
+	fallbackResult, err := backend.DownloadWithFallback(chain, providerReq)
 	if err != nil {
 		backend.FailDownloadItem(itemID, fmt.Sprintf("Download failed: %v", err))
-
-		if filename != "" && !strings.HasPrefix(filename, "EXISTS:") {
-
-			if _, statErr := os.Stat(filename); statErr == nil {
-				fmt.Printf("Removing corrupted/partial file after failed download: %s\n", filename)
-				if removeErr := os.Remove(filename); removeErr != nil {
-					fmt.Printf("Warning: Failed to remove corrupted file %s: %v\n", filename, removeErr)
-				}
-			}
-		}
-
 		return DownloadResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Download failed: %v", err),
-			ItemID:  itemID,
+			Success:          false,
+			Error:            fmt.Sprintf("Download failed: %v", err),
+			ItemID:           itemID,
+			SkippedProviders: fallbackResult.Skipped,
 		}, err
 	}
+	filename = fallbackResult.Filename
 
 	alreadyExists := false
 	if strings.HasPrefix(filename, "EXISTS:") {
@@ -419,8 +394,47 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		filename = strings.TrimPrefix(filename, "EXISTS:")
 	}
 
-	if !alreadyExists && req.SpotifyID != "" && req.EmbedLyrics && strings.HasSuffix(filename, ".flac") {
-		go func(filePath, spotifyID, trackName, artistName string) {
+	var verification *backend.VerificationResult
+	if !alreadyExists && strings.HasSuffix(filename, ".flac") {
+		verification, err = backend.VerifyFLAC(filename, int64(req.Duration)*1000)
+		if err != nil {
+			verification = &backend.VerificationResult{
+				FilePath: filename,
+				Issues:   []string{err.Error()},
+			}
+		}
+
+		if !verification.Valid {
+			reason := strings.Join(verification.Issues, "; ")
+			fmt.Printf("Verification failed for %s: %s\n", filename, reason)
+			if renameErr := os.Rename(filename, filename+".corrupt"); renameErr != nil {
+				os.Remove(filename)
+			}
+
+			backend.FailDownloadItem(itemID, fmt.Sprintf("Verification failed: %s", reason))
+			return DownloadResponse{
+				Success:      false,
+				Error:        fmt.Sprintf("Verification failed: %s", reason),
+				ItemID:       itemID,
+				Provider:     fallbackResult.Service,
+				Quality:      fallbackResult.Quality,
+				Verification: verification,
+			}, fmt.Errorf("verification failed: %s", reason)
+		}
+	}
+
+	if !alreadyExists && req.ReplayGain && strings.HasSuffix(filename, ".flac") {
+		rgResult, rgErr := backend.ApplyReplayGain([]string{filename}, false)
+		if rgErr != nil {
+			fmt.Printf("Warning: ReplayGain scan failed: %v\n", rgErr)
+		}
+		for _, msg := range rgResult.Errors {
+			fmt.Printf("Warning: ReplayGain: %s\n", msg)
+		}
+	}
+
+	if !alreadyExists && req.SpotifyID != "" && (req.EmbedLyrics || req.LyricsSidecar) {
+		go func(filePath, spotifyID, trackName, artistName string, duration int, embed, sidecar bool, lyricsFormat string, trackReq backend.TrackRequest) {
 			fmt.Printf("\n========== LYRICS FETCH START ==========\n")
 			fmt.Printf("Spotify ID: %s\n", spotifyID)
 			fmt.Printf("Track: %s\n", trackName)
@@ -429,12 +443,23 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 
 			lyricsClient := backend.NewLyricsClient()
 
-			lyricsResp, source, err := lyricsClient.FetchLyricsAllSources(spotifyID, trackName, artistName, 0)
+			searchResult, err := lyricsClient.SearchLyrics(backend.LyricsQuery{
+				SpotifyID:  spotifyID,
+				TrackName:  trackName,
+				ArtistName: artistName,
+				AlbumName:  trackReq.Tags.Album,
+				Duration:   duration,
+				Dir:        filepath.Dir(filePath),
+			})
+			for _, candidate := range searchResult.Candidates {
+				fmt.Printf("   Candidate [%.2f] %s: %s - %s (%.0fs) %v\n", candidate.Score, candidate.Source, candidate.ArtistName, candidate.TrackName, candidate.Duration, candidate.Reasons)
+			}
 			if err != nil {
 				fmt.Printf("All sources failed: %v\n", err)
 				fmt.Printf("========== LYRICS FETCH END (FAILED) ==========\n\n")
 				return
 			}
+			lyricsResp, source := searchResult.Best.Lyrics, searchResult.Best.Source
 
 			if lyricsResp == nil || len(lyricsResp.Lines) == 0 {
 				fmt.Println("No lyrics content found")
@@ -457,15 +482,33 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 			fmt.Println(lyrics)
 			fmt.Printf("--- End LRC Content ---\n\n")
 
-			fmt.Printf("Embedding into: %s\n", filePath)
-			if err := backend.EmbedLyricsOnly(filePath, lyrics); err != nil {
-				fmt.Printf("Failed to embed lyrics: %v\n", err)
+			failed := false
+			if sidecar {
+				sidecarPath, err := lyricsClient.SaveLyricsSidecar(filePath, lyricsResp, trackReq, lyricsFormat)
+				if err != nil {
+					fmt.Printf("Failed to write lyrics file: %v\n", err)
+					failed = true
+				} else {
+					fmt.Printf("Lyrics file saved: %s\n", sidecarPath)
+				}
+			}
+
+			if embed {
+				fmt.Printf("Embedding into: %s\n", filePath)
+				if err := backend.EmbedLyricsOnlyUniversal(filePath, lyrics); err != nil {
+					fmt.Printf("Failed to embed lyrics: %v\n", err)
+					failed = true
+				} else {
+					fmt.Printf("Lyrics embedded successfully!\n")
+				}
+			}
+
+			if failed {
 				fmt.Printf("========== LYRICS FETCH END (FAILED) ==========\n\n")
 			} else {
-				fmt.Printf("Lyrics embedded successfully!\n")
 				fmt.Printf("========== LYRICS FETCH END (SUCCESS) ==========\n\n")
 			}
-		}(filename, req.SpotifyID, req.TrackName, req.ArtistName)
+		}(filename, req.SpotifyID, req.TrackName, req.ArtistName, req.Duration, req.EmbedLyrics, req.LyricsSidecar, req.LyricsFormat, trackReq)
 	}
 
 	message := "Download completed successfully"
@@ -474,12 +517,16 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 		backend.SkipDownloadItem(itemID, filename)
 	} else {
 
+		finalSize := 0.0
 		if fileInfo, statErr := os.Stat(filename); statErr == nil {
-			finalSize := float64(fileInfo.Size()) / (1024 * 1024)
-			backend.CompleteDownloadItem(itemID, filename, finalSize)
-		} else {
+			finalSize = float64(fileInfo.Size()) / (1024 * 1024)
+		}
 
-			backend.CompleteDownloadItem(itemID, filename, 0)
+		if verification != nil && verification.Suspect {
+			message = "Download completed with warnings"
+			backend.SuspectDownloadItem(itemID, filename, finalSize, strings.Join(verification.Issues, "; "))
+		} else {
+			backend.CompleteDownloadItem(itemID, filename, finalSize)
 		}
 
 		go func(fPath, track, artist, album, sID, cover, format string) {
@@ -514,15 +561,23 @@ func (a *App) DownloadTrack(req DownloadRequest) (DownloadResponse, error) {
 				}
 			}
 			backend.AddHistoryItem(item, "SpotiFLAC")
-		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, req.AudioFormat)
+
+			if err := backend.IndexLibraryFile(fPath); err != nil {
+				fmt.Printf("Warning: Failed to index downloaded file: %v\n", err)
+			}
+		}(filename, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID, req.CoverURL, fallbackResult.Quality)
 	}
 
 	return DownloadResponse{
-		Success:       true,
-		Message:       message,
-		File:          filename,
-		AlreadyExists: alreadyExists,
-		ItemID:        itemID,
+		Success:          true,
+		Message:          message,
+		File:             filename,
+		AlreadyExists:    alreadyExists,
+		ItemID:           itemID,
+		Provider:         fallbackResult.Service,
+		Quality:          fallbackResult.Quality,
+		SkippedProviders: fallbackResult.Skipped,
+		Verification:     verification,
 	}, nil
 }
 
@@ -553,6 +608,10 @@ func (a *App) GetDefaults() map[string]string {
 	}
 }
 
+func (a *App) GetProviders() []backend.ProviderInfo {
+	return backend.ListProviders()
+}
+
 func (a *App) GetDownloadProgress() backend.ProgressInfo {
 	return backend.GetDownloadProgress()
 }
@@ -575,6 +634,100 @@ func (a *App) AddToDownloadQueue(isrc, trackName, artistName, albumName string)
 	return itemID
 }
 
+func (a *App) QueueDownloadRequest(req DownloadRequest) string {
+	itemID := req.ItemID
+	if itemID == "" {
+		if req.SpotifyID != "" {
+			itemID = fmt.Sprintf("%s-%d", req.SpotifyID, time.Now().UnixNano())
+		} else {
+			itemID = fmt.Sprintf("%s-%s-%d", req.TrackName, req.ArtistName, time.Now().UnixNano())
+		}
+		req.ItemID = itemID
+	}
+
+	backend.AddToQueue(itemID, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID)
+	if err := backend.PersistDownloadRequest(itemID, req.TrackName, req.ArtistName, req.AlbumName, req); err != nil {
+		fmt.Printf("Warning: Failed to persist download request: %v\n", err)
+	}
+	return itemID
+}
+
+func (a *App) GetResumableDownloads() ([]backend.PersistedDownload, error) {
+	return backend.GetPersistedDownloads()
+}
+
+func (a *App) ResumeDownloads(itemIDs []string) (int, error) {
+	pending, err := backend.GetPersistedDownloads()
+	if err != nil {
+		return 0, err
+	}
+
+	selected := make(map[string]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		selected[id] = true
+	}
+
+	var requests []DownloadRequest
+	for _, item := range pending {
+		if len(selected) > 0 && !selected[item.ID] {
+			continue
+		}
+
+		var req DownloadRequest
+		if err := json.Unmarshal(item.Request, &req); err != nil {
+			fmt.Printf("Skipping unreadable queued download %s: %v\n", item.ID, err)
+			continue
+		}
+		req.ItemID = item.ID
+
+		backend.AddToQueue(item.ID, req.TrackName, req.ArtistName, req.AlbumName, req.SpotifyID)
+		requests = append(requests, req)
+	}
+
+	a.scheduleDownloads(requests)
+	return len(requests), nil
+}
+
+func (a *App) EnqueueDownloads(reqs []DownloadRequest) []string {
+	itemIDs := make([]string, 0, len(reqs))
+	for i := range reqs {
+		reqs[i].ItemID = a.QueueDownloadRequest(reqs[i])
+		itemIDs = append(itemIDs, reqs[i].ItemID)
+	}
+
+	a.scheduleDownloads(reqs)
+	return itemIDs
+}
+
+func (a *App) scheduleDownloads(reqs []DownloadRequest) {
+	jobs := make([]backend.DownloadJob, 0, len(reqs))
+	for _, req := range reqs {
+		req := req
+		jobs = append(jobs, backend.DownloadJob{
+			ItemID: req.ItemID,
+			Run: func() {
+				a.DownloadTrack(req)
+			},
+		})
+	}
+
+	a.scheduler.Enqueue(jobs...)
+}
+
+func (a *App) SetDownloadConcurrency(maxWorkers int, perProvider map[string]int) {
+	a.scheduler.SetMaxWorkers(maxWorkers)
+	for service, n := range perProvider {
+		a.scheduler.SetProviderConcurrency(service, n)
+	}
+}
+
+func (a *App) DiscardResumableDownloads(itemIDs []string) error {
+	if len(itemIDs) == 0 {
+		return backend.ClearPersistedDownloads()
+	}
+	return backend.DeletePersistedDownloads(itemIDs)
+}
+
 func (a *App) MarkDownloadItemFailed(itemID, errorMsg string) {
 	backend.FailDownloadItem(itemID, errorMsg)
 }
@@ -630,7 +783,15 @@ func (a *App) AnalyzeMultipleTracks(filePaths []string) (string, error) {
 		results = append(results, result)
 	}
 
-	jsonData, err := json.Marshal(results)
+	response := struct {
+		Tracks      []*backend.AnalysisResult `json:"tracks"`
+		AlbumReport *backend.AlbumDRReport    `json:"album_report"`
+	}{
+		Tracks:      results,
+		AlbumReport: backend.BuildAlbumDRReport(results),
+	}
+
+	jsonData, err := json.Marshal(response)
 	if err != nil {
 		return "", fmt.Errorf("failed to encode response: %v", err)
 	}
@@ -638,6 +799,30 @@ func (a *App) AnalyzeMultipleTracks(filePaths []string) (string, error) {
 	return string(jsonData), nil
 }
 
+func (a *App) ScanReplayGain(filePaths []string, albumMode bool) (*backend.ReplayGainResult, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("at least one file path is required")
+	}
+
+	return backend.ScanReplayGain(filePaths, albumMode), nil
+}
+
+func (a *App) ApplyReplayGain(filePaths []string, albumMode bool) (*backend.ReplayGainResult, error) {
+	if len(filePaths) == 0 {
+		return nil, fmt.Errorf("at least one file path is required")
+	}
+
+	return backend.ApplyReplayGain(filePaths, albumMode)
+}
+
+func (a *App) ExportSpectrogram(filePath, outputPath string, opts backend.SpectrogramOptions) (string, error) {
+	if filePath == "" {
+		return "", fmt.Errorf("file path is required")
+	}
+
+	return backend.ExportSpectrogramPNG(filePath, outputPath, opts)
+}
+
 type LyricsDownloadRequest struct {
 	SpotifyID           string `json:"spotify_id"`
 	TrackName           string `json:"track_name"`
@@ -651,6 +836,7 @@ type LyricsDownloadRequest struct {
 	Position            int    `json:"position"`
 	UseAlbumTrackNumber bool   `json:"use_album_track_number"`
 	DiscNumber          int    `json:"disc_number"`
+	Format              string `json:"format,omitempty"`
 }
 
 func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadResponse, error) {
@@ -675,10 +861,14 @@ func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadR
 		Position:            req.Position,
 		UseAlbumTrackNumber: req.UseAlbumTrackNumber,
 		DiscNumber:          req.DiscNumber,
+		Format:              req.Format,
 	}
 
 	resp, err := client.DownloadLyrics(backendReq)
 	if err != nil {
+		if resp != nil {
+			return *resp, err
+		}
 		return backend.LyricsDownloadResponse{
 			Success: false,
 			Error:   err.Error(),
@@ -688,6 +878,31 @@ func (a *App) DownloadLyrics(req LyricsDownloadRequest) (backend.LyricsDownloadR
 	return *resp, nil
 }
 
+func (a *App) AlignLyrics(filePath string, opts backend.LyricsAlignOptions) (*backend.LyricsAlignment, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("file path is required")
+	}
+
+	return backend.AlignLyricsFile(filePath, opts)
+}
+
+func (a *App) AlignLyricsFolder(folderPath string, opts backend.LyricsAlignOptions) (*backend.LyricsAlignBatchResult, error) {
+	if folderPath == "" {
+		return nil, fmt.Errorf("folder path is required")
+	}
+
+	return backend.AlignLyricsFolder(folderPath, opts)
+}
+
+func (a *App) SearchLyrics(query backend.LyricsQuery) (*backend.LyricsSearchResult, error) {
+	if query.TrackName == "" || query.ArtistName == "" {
+		return nil, fmt.Errorf("track name and artist name are required")
+	}
+
+	client := backend.NewLyricsClient()
+	return client.SearchLyrics(query)
+}
+
 type CoverDownloadRequest struct {
 	CoverURL       string `json:"cover_url"`
 	TrackName      string `json:"track_name"`
@@ -964,6 +1179,160 @@ func (a *App) ReadFileMetadata(filePath string) (*backend.AudioMetadata, error)
 	return backend.ReadAudioMetadata(filePath)
 }
 
+func (a *App) GetLibraryRoots() ([]backend.LibraryRoot, error) {
+	return backend.GetLibraryRoots()
+}
+
+func (a *App) AddLibraryRoot(folderPath string) error {
+	if folderPath == "" {
+		return fmt.Errorf("folder path is required")
+	}
+	return backend.AddLibraryRoot(folderPath)
+}
+
+func (a *App) RemoveLibraryRoot(folderPath string) error {
+	if folderPath == "" {
+		return fmt.Errorf("folder path is required")
+	}
+	return backend.RemoveLibraryRoot(folderPath)
+}
+
+func (a *App) ScanLibrary(full bool) ([]backend.LibraryScanResult, error) {
+	return backend.ScanLibrary(full)
+}
+
+func (a *App) ScanLibraryFolder(folderPath string, full bool) (*backend.LibraryScanResult, error) {
+	if folderPath == "" {
+		return nil, fmt.Errorf("folder path is required")
+	}
+	return backend.ScanLibraryRoot(folderPath, full)
+}
+
+func (a *App) StartLibraryWatcher() error {
+	return backend.StartLibraryWatcher()
+}
+
+func (a *App) StopLibraryWatcher() {
+	backend.StopLibraryWatcher()
+}
+
+func (a *App) GetLibraryWatcherStatus() backend.LibraryWatcherStatus {
+	return backend.GetLibraryWatcherStatus()
+}
+
+func (a *App) SearchLibrary(query string, limit int) ([]backend.LibraryTrack, error) {
+	return backend.SearchLibrary(query, limit)
+}
+
+func (a *App) FindLibraryTrack(query backend.LibraryQuery) (*backend.LibraryMatch, error) {
+	return backend.FindLibraryTrack(query)
+}
+
+type SyncJobRequest struct {
+	Name           string          `json:"name,omitempty"`
+	URL            string          `json:"url"`
+	OutputDir      string          `json:"output_dir"`
+	ArchiveRemoved bool            `json:"archive_removed,omitempty"`
+	ArchiveDir     string          `json:"archive_dir,omitempty"`
+	WritePlaylist  bool            `json:"write_playlist,omitempty"`
+	Download       DownloadRequest `json:"download"`
+}
+
+func (a *App) CreateSyncJob(req SyncJobRequest) (*backend.SyncJob, error) {
+	if req.URL == "" {
+		return nil, fmt.Errorf("URL is required")
+	}
+
+	template, err := json.Marshal(req.Download)
+	if err != nil {
+		return nil, err
+	}
+
+	return backend.CreateSyncJob(backend.SyncJob{
+		Name:           req.Name,
+		URL:            req.URL,
+		OutputDir:      req.OutputDir,
+		ArchiveRemoved: req.ArchiveRemoved,
+		ArchiveDir:     req.ArchiveDir,
+		WritePlaylist:  req.WritePlaylist,
+		Request:        template,
+	})
+}
+
+func (a *App) ListSyncJobs() ([]backend.SyncJob, error) {
+	return backend.ListSyncJobs()
+}
+
+func (a *App) DeleteSyncJob(jobID string) error {
+	if jobID == "" {
+		return fmt.Errorf("job ID is required")
+	}
+	return backend.DeleteSyncJob(jobID)
+}
+
+func (a *App) RunSyncJob(jobID string) (*backend.SyncReport, error) {
+	if jobID == "" {
+		return nil, fmt.Errorf("job ID is required")
+	}
+
+	job, err := backend.GetSyncJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	var template DownloadRequest
+	if len(job.Request) > 0 {
+		if err := json.Unmarshal(job.Request, &template); err != nil {
+			return nil, fmt.Errorf("invalid download settings for sync job: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 6*time.Hour)
+	defer cancel()
+
+	return backend.RunSyncJob(ctx, jobID, func(downloads []backend.SyncDownload) []string {
+		reqs := make([]DownloadRequest, 0, len(downloads))
+		for _, d := range downloads {
+			track := d.Track
+			req := template
+			req.ItemID = ""
+			req.OutputDir = job.OutputDir
+			req.SpotifyID = track.SpotifyID
+			req.ISRC = track.ISRC
+			req.TrackName = track.Name
+			req.ArtistName = track.Artists
+			req.AlbumName = track.AlbumName
+			req.AlbumArtist = track.AlbumArtist
+			req.ReleaseDate = track.ReleaseDate
+			req.CoverURL = track.Images
+			req.Position = d.Position
+			req.Duration = track.DurationMS / 1000
+			req.SpotifyTrackNumber = track.TrackNumber
+			req.SpotifyDiscNumber = track.DiscNumber
+			req.SpotifyTotalTracks = track.TotalTracks
+			req.SpotifyTotalDiscs = track.TotalDiscs
+			reqs = append(reqs, req)
+		}
+
+		return a.EnqueueDownloads(reqs)
+	})
+}
+
+func (a *App) ExportPlaylist(req backend.PlaylistExportRequest) (*backend.PlaylistExportResult, error) {
+	return backend.ExportPlaylist(req)
+}
+
+func (a *App) PlanDiscographyDownload(artistURL string, options backend.DiscographyPlanOptions) (*backend.DiscographyPlan, error) {
+	if artistURL == "" {
+		return nil, fmt.Errorf("artist URL is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	return backend.PlanDiscographyDownload(ctx, artistURL, options)
+}
+
 func (a *App) PreviewRenameFiles(files []string, format string) []backend.RenamePreview {
 	return backend.PreviewRename(files, format)
 }
@@ -984,7 +1353,14 @@ func (a *App) RenameFileTo(oldPath, newName string) error {
 	dir := filepath.Dir(oldPath)
 	ext := filepath.Ext(oldPath)
 	newPath := filepath.Join(dir, newName+ext)
-	return os.Rename(oldPath, newPath)
+	if err := os.Rename(oldPath, newPath); err != nil {
+		return err
+	}
+
+	if err := backend.MoveLibraryFile(oldPath, newPath); err != nil {
+		fmt.Printf("[Library] Failed to update renamed file: %v\n", err)
+	}
+	return nil
 }
 
 func (a *App) ReadImageAsBase64(filePath string) (string, error) {
@@ -1026,6 +1402,8 @@ type CheckFileExistenceRequest struct {
 	FilenameFormat      string `json:"filename_format,omitempty"`
 	IncludeTrackNumber  bool   `json:"include_track_number,omitempty"`
 	AudioFormat         string `json:"audio_format,omitempty"`
+	ISRC                string `json:"isrc,omitempty"`
+	Duration            int    `json:"duration,omitempty"`
 }
 
 type CheckFileExistenceResult struct {
@@ -1034,6 +1412,7 @@ type CheckFileExistenceResult struct {
 	FilePath   string `json:"file_path,omitempty"`
 	TrackName  string `json:"track_name,omitempty"`
 	ArtistName string `json:"artist_name,omitempty"`
+	MatchedBy  string `json:"matched_by,omitempty"`
 }
 
 func (a *App) CheckFilesExistence(outputDir string, tracks []CheckFileExistenceRequest) []CheckFileExistenceResult {
@@ -1081,18 +1460,20 @@ func (a *App) CheckFilesExistence(outputDir string, tracks []CheckFileExistenceR
 				fileExt = ".mp3"
 			}
 
-			expectedFilenameBase := backend.BuildExpectedFilename(
-				t.TrackName,
-				t.ArtistName,
-				t.AlbumName,
-				t.AlbumArtist,
-				t.ReleaseDate,
-				filenameFormat,
-				t.IncludeTrackNumber,
-				trackNumber,
-				t.DiscNumber,
-				t.UseAlbumTrackNumber,
-			)
+			expectedFilenameBase := backend.BuildExpectedFilename(backend.TrackRequest{
+				FilenameFormat:      filenameFormat,
+				IncludeTrackNumber:  t.IncludeTrackNumber,
+				Position:            trackNumber,
+				UseAlbumTrackNumber: t.UseAlbumTrackNumber,
+				Tags: backend.TrackTags{
+					Title:       t.TrackName,
+					Artist:      t.ArtistName,
+					Album:       t.AlbumName,
+					AlbumArtist: t.AlbumArtist,
+					ReleaseDate: t.ReleaseDate,
+					DiscNumber:  t.DiscNumber,
+				},
+			})
 
 			expectedFilename := strings.TrimSuffix(expectedFilenameBase, ".flac") + fileExt
 
@@ -1101,6 +1482,17 @@ func (a *App) CheckFilesExistence(outputDir string, tracks []CheckFileExistenceR
 			if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 100*1024 {
 				res.Exists = true
 				res.FilePath = expectedPath
+				res.MatchedBy = "filename"
+			} else if match, err := backend.FindLibraryTrack(backend.LibraryQuery{
+				SpotifyID: t.SpotifyID,
+				ISRC:      t.ISRC,
+				Title:     t.TrackName,
+				Artist:    t.ArtistName,
+				Duration:  t.Duration,
+			}); err == nil && match != nil {
+				res.Exists = true
+				res.FilePath = match.Track.Path
+				res.MatchedBy = match.MatchedBy
 			}
 
 			resultsChan <- result{index: idx, result: res}
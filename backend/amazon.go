@@ -205,7 +205,7 @@ func (a *AmazonDownloader) extractData(html string, patterns []string) string {
 	return ""
 }
 
-func (a *AmazonDownloader) DownloadFromLucida(amazonURL, outputDir, quality string) (string, error) {
+func (a *AmazonDownloader) DownloadFromLucida(amazonURL, outputDir, quality, itemID string) (string, error) {
 	tr := &http.Transport{
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}
@@ -351,29 +351,21 @@ func (a *AmazonDownloader) DownloadFromLucida(amazonURL, outputDir, quality stri
 	}
 
 	filePath := filepath.Join(outputDir, fileName)
-	out, err := os.Create(filePath)
-	if err != nil {
-		return "", err
-	}
-	defer out.Close()
 
 	fmt.Printf("Downloading from Lucida: %s\n", fileName)
 
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, resp.Body)
+	total, err := DownloadResumableResponse(client, resp, filePath, itemID)
 	if err != nil {
-		out.Close()
-		os.Remove(filePath)
-		return "", fmt.Errorf("failed to write file: %w", err)
+		return "", err
 	}
 
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
+	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(total)/(1024*1024))
 	return filePath, nil
 }
 
-func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality string) (string, error) {
+func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality, itemID string) (string, error) {
 	fmt.Println("Attempting download via Lucida (Priority)...")
-	filePath, err := a.DownloadFromLucida(amazonURL, outputDir, quality)
+	filePath, err := a.DownloadFromLucida(amazonURL, outputDir, quality, itemID)
 	if err == nil {
 		return filePath, nil
 	}
@@ -495,9 +487,9 @@ func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality str
 					lastError = fmt.Errorf("failed to download file: %w", err)
 					break
 				}
-				defer fileResp.Body.Close()
 
 				if fileResp.StatusCode != 200 {
+					fileResp.Body.Close()
 					lastError = fmt.Errorf("download failed with status %d", fileResp.StatusCode)
 					break
 				}
@@ -510,23 +502,15 @@ func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality str
 
 				filePath := filepath.Join(outputDir, fileName)
 
-				out, err := os.Create(filePath)
-				if err != nil {
-					lastError = fmt.Errorf("failed to create file: %w", err)
-					break
-				}
-				defer out.Close()
-
 				fmt.Println("Downloading...")
 
-				pw := NewProgressWriter(out)
-				_, err = io.Copy(pw, fileResp.Body)
+				total, err := DownloadResumableResponse(a.client, fileResp, filePath, itemID)
 				if err != nil {
-					out.Close()
-					return "", fmt.Errorf("failed to write file: %w", err)
+					lastError = err
+					break
 				}
 
-				fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
+				fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(total)/(1024*1024))
 				fmt.Println("Download complete!")
 				return filePath, nil
 
@@ -561,17 +545,19 @@ func (a *AmazonDownloader) DownloadFromService(amazonURL, outputDir, quality str
 	return "", fmt.Errorf("all regions failed. Last error: %v", lastError)
 }
 
-func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL string, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, embedMaxQualityCover bool, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
+func (a *AmazonDownloader) DownloadByURL(amazonURL string, req TrackRequest) (string, error) {
 
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
 			return "", fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
 
-	if spotifyTrackName != "" && spotifyArtistName != "" {
-		expectedFilename := BuildExpectedFilename(spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, filenameFormat, includeTrackNumber, position, spotifyDiscNumber, false)
-		expectedPath := filepath.Join(outputDir, expectedFilename)
+	hasSpotifyTags := req.Tags.Title != "" && req.Tags.Artist != ""
+
+	if hasSpotifyTags {
+		expectedFilename := BuildExpectedFilename(req)
+		expectedPath := filepath.Join(req.OutputDir, expectedFilename)
 
 		if fileInfo, err := os.Stat(expectedPath); err == nil && fileInfo.Size() > 0 {
 			fmt.Printf("File already exists: %s (%.2f MB)\n", expectedPath, float64(fileInfo.Size())/(1024*1024))
@@ -581,64 +567,14 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 
 	fmt.Printf("Using Amazon URL: %s\n", amazonURL)
 
-	filePath, err := a.DownloadFromService(amazonURL, outputDir, quality)
+	filePath, err := a.DownloadFromService(amazonURL, req.OutputDir, req.Quality, req.ItemID)
 	if err != nil {
 		return "", err
 	}
 
-	if spotifyTrackName != "" && spotifyArtistName != "" {
-		safeArtist := sanitizeFilename(spotifyArtistName)
-		safeTitle := sanitizeFilename(spotifyTrackName)
-		safeAlbum := sanitizeFilename(spotifyAlbumName)
-		safeAlbumArtist := sanitizeFilename(spotifyAlbumArtist)
-
-		year := ""
-		if len(spotifyReleaseDate) >= 4 {
-			year = spotifyReleaseDate[:4]
-		}
-
-		var newFilename string
-
-		if strings.Contains(filenameFormat, "{") {
-			newFilename = filenameFormat
-			newFilename = strings.ReplaceAll(newFilename, "{title}", safeTitle)
-			newFilename = strings.ReplaceAll(newFilename, "{artist}", safeArtist)
-			newFilename = strings.ReplaceAll(newFilename, "{album}", safeAlbum)
-			newFilename = strings.ReplaceAll(newFilename, "{album_artist}", safeAlbumArtist)
-			newFilename = strings.ReplaceAll(newFilename, "{year}", year)
-
-			if spotifyDiscNumber > 0 {
-				newFilename = strings.ReplaceAll(newFilename, "{disc}", fmt.Sprintf("%d", spotifyDiscNumber))
-			} else {
-				newFilename = strings.ReplaceAll(newFilename, "{disc}", "")
-			}
-
-			if position > 0 {
-				newFilename = strings.ReplaceAll(newFilename, "{track}", fmt.Sprintf("%02d", position))
-			} else {
-
-				newFilename = regexp.MustCompile(`\{track\}\.\s*`).ReplaceAllString(newFilename, "")
-				newFilename = regexp.MustCompile(`\{track\}\s*-\s*`).ReplaceAllString(newFilename, "")
-				newFilename = regexp.MustCompile(`\{track\}\s*`).ReplaceAllString(newFilename, "")
-			}
-		} else {
-
-			switch filenameFormat {
-			case "artist-title":
-				newFilename = fmt.Sprintf("%s - %s", safeArtist, safeTitle)
-			case "title":
-				newFilename = safeTitle
-			default:
-				newFilename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
-			}
-
-			if includeTrackNumber && position > 0 {
-				newFilename = fmt.Sprintf("%02d. %s", position, newFilename)
-			}
-		}
-
-		newFilename = newFilename + ".flac"
-		newFilePath := filepath.Join(outputDir, newFilename)
+	if hasSpotifyTags {
+		newFilename := BuildExpectedFilename(req)
+		newFilePath := filepath.Join(req.OutputDir, newFilename)
 
 		if err := os.Rename(filePath, newFilePath); err != nil {
 			fmt.Printf("Warning: Failed to rename file: %v\n", err)
@@ -652,10 +588,10 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = filePath + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -664,28 +600,7 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
-	if trackNumberToEmbed == 0 {
-		trackNumberToEmbed = 1
-	}
-
-	metadata := Metadata{
-		Title:       spotifyTrackName,
-		Artist:      spotifyArtistName,
-		Album:       spotifyAlbumName,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
-		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
-		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
-		Description: "https://github.com/afkarxyz/SpotiFLAC",
-	}
-
-	if err := EmbedMetadata(filePath, metadata, coverPath); err != nil {
+	if err := EmbedMetadata(filePath, req.Tags.ToMetadata(), coverPath); err != nil {
 		fmt.Printf("Warning: Failed to embed metadata: %v\n", err)
 	} else {
 		fmt.Println("Metadata embedded successfully")
@@ -696,12 +611,47 @@ func (a *AmazonDownloader) DownloadByURL(amazonURL, outputDir, quality, filename
 	return filePath, nil
 }
 
-func (a *AmazonDownloader) DownloadBySpotifyID(spotifyTrackID, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL string, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, embedMaxQualityCover bool, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
+func (a *AmazonDownloader) DownloadBySpotifyID(spotifyTrackID string, req TrackRequest) (string, error) {
 
 	amazonURL, err := a.GetAmazonURLFromSpotify(spotifyTrackID)
 	if err != nil {
 		return "", err
 	}
 
-	return a.DownloadByURL(amazonURL, outputDir, quality, filenameFormat, includeTrackNumber, position, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, spotifyCoverURL, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks, embedMaxQualityCover, spotifyTotalDiscs, spotifyCopyright, spotifyPublisher, spotifyURL)
+	return a.DownloadByURL(amazonURL, req)
+}
+
+func (a *AmazonDownloader) Name() string {
+	return "amazon"
+}
+
+func (a *AmazonDownloader) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		ResolveBySpotifyID: true,
+		ResolveByURL:       true,
+	}
+}
+
+func (a *AmazonDownloader) Qualities() []string {
+	return []string{"LOSSLESS"}
+}
+
+func (a *AmazonDownloader) ResolveTrack(req ProviderRequest) (string, error) {
+	if req.ServiceURL != "" {
+		return req.ServiceURL, nil
+	}
+
+	if req.Tags.SpotifyID == "" {
+		return "", fmt.Errorf("spotify ID is required for Amazon Music")
+	}
+
+	return a.GetAmazonURLFromSpotify(req.Tags.SpotifyID)
+}
+
+func (a *AmazonDownloader) ProbeQuality(trackRef string) (string, error) {
+	return "LOSSLESS", nil
+}
+
+func (a *AmazonDownloader) FetchTrack(trackRef string, req ProviderRequest) (string, error) {
+	return a.DownloadByURL(trackRef, req.TrackRequest)
 }
@@ -2,26 +2,28 @@ package backend
 
 import (
 	"fmt"
+	"io"
 	"math"
 	"os"
-
-	"github.com/go-flac/go-flac"
-	mewflac "github.com/mewkiz/flac"
+	"strings"
 )
 
 type AnalysisResult struct {
-	FilePath      string        `json:"file_path"`
-	FileSize      int64         `json:"file_size"`
-	SampleRate    uint32        `json:"sample_rate"`
-	Channels      uint8         `json:"channels"`
-	BitsPerSample uint8         `json:"bits_per_sample"`
-	TotalSamples  uint64        `json:"total_samples"`
-	Duration      float64       `json:"duration"`
-	BitDepth      string        `json:"bit_depth"`
-	DynamicRange  float64       `json:"dynamic_range"`
-	PeakAmplitude float64       `json:"peak_amplitude"`
-	RMSLevel      float64       `json:"rms_level"`
-	Spectrum      *SpectrumData `json:"spectrum,omitempty"`
+	FilePath      string              `json:"file_path"`
+	FileSize      int64               `json:"file_size"`
+	SampleRate    uint32              `json:"sample_rate"`
+	Channels      uint8               `json:"channels"`
+	BitsPerSample uint8               `json:"bits_per_sample"`
+	TotalSamples  uint64              `json:"total_samples"`
+	Duration      float64             `json:"duration"`
+	BitDepth      string              `json:"bit_depth"`
+	Codec         string              `json:"codec,omitempty"`
+	DynamicRange  float64             `json:"dynamic_range"`
+	PeakAmplitude float64             `json:"peak_amplitude"`
+	RMSLevel      float64             `json:"rms_level"`
+	Spectrum      *SpectrumData       `json:"spectrum,omitempty"`
+	Authenticity  *AuthenticityResult `json:"authenticity,omitempty"`
+	DR            *DRResult           `json:"dr,omitempty"`
 }
 
 func AnalyzeTrack(filepath string) (*AnalysisResult, error) {
@@ -34,125 +36,116 @@ func AnalyzeTrack(filepath string) (*AnalysisResult, error) {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	f, err := flac.ParseFile(filepath)
+	info, err := ProbeAudio(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
 
 	result := &AnalysisResult{
 		FilePath: filepath,
 		FileSize: fileInfo.Size(),
 	}
+	applyAudioInfo(result, info)
 
-	if len(f.Meta) > 0 {
-		streamInfo := f.Meta[0]
-		if streamInfo.Type == flac.StreamInfo {
-
-			data := streamInfo.Data
-			if len(data) >= 18 {
-
-				result.SampleRate = uint32(data[10])<<12 | uint32(data[11])<<4 | uint32(data[12])>>4
-
-				result.Channels = ((data[12] >> 1) & 0x07) + 1
-
-				result.BitsPerSample = ((data[12]&0x01)<<4 | data[13]>>4) + 1
-
-				result.TotalSamples = uint64(data[13]&0x0F)<<32 |
-					uint64(data[14])<<24 |
-					uint64(data[15])<<16 |
-					uint64(data[16])<<8 |
-					uint64(data[17])
-
-				if result.SampleRate > 0 {
-					result.Duration = float64(result.TotalSamples) / float64(result.SampleRate)
-				}
-
-			}
-		}
-	}
-
-	spectrum, err := AnalyzeSpectrum(filepath)
+	effectiveBits, err := analyzeSamples(result, filepath)
 	if err != nil {
-
-		fmt.Printf("Warning: failed to analyze spectrum: %v\n", err)
-	} else {
-		result.Spectrum = spectrum
-
-		calculateRealAudioMetrics(result, filepath)
+		fmt.Printf("Warning: failed to analyze audio: %v\n", err)
 	}
 
-	result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
+	if isLosslessCodec(info.Codec) {
+		result.Authenticity = DetectFakeLossless(result.Spectrum, result.BitsPerSample, effectiveBits)
+	}
 
 	return result, nil
 }
 
-func calculateRealAudioMetrics(result *AnalysisResult, filepath string) {
+func applyAudioInfo(result *AnalysisResult, info AudioInfo) {
+	result.Codec = info.Codec
+	result.SampleRate = uint32(info.SampleRate)
+	result.Channels = uint8(info.Channels)
+	result.BitsPerSample = uint8(info.BitsPerSample)
+	result.TotalSamples = info.TotalSamples
+	result.Duration = info.Duration
 
-	samples, err := decodeFLACForMetrics(filepath)
-	if err != nil {
-		return
+	if result.BitsPerSample > 0 {
+		result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
+	} else {
+		result.BitDepth = "N/A"
 	}
+}
 
-	var peak float64
-	var sumSquares float64
-
-	for _, sample := range samples {
-		absVal := sample
-		if absVal < 0 {
-			absVal = -absVal
-		}
-		if absVal > peak {
-			peak = absVal
-		}
-		sumSquares += sample * sample
+func isLosslessCodec(codec string) bool {
+	switch codec {
+	case "flac", "alac", "wavpack", "ape", "tta":
+		return true
 	}
+	return strings.HasPrefix(codec, "pcm_")
+}
 
-	peakDB := 20.0 * math.Log10(peak)
-	result.PeakAmplitude = peakDB
-
-	rms := math.Sqrt(sumSquares / float64(len(samples)))
-	rmsDB := 20.0 * math.Log10(rms)
-	result.RMSLevel = rmsDB
+func analyzeSamples(result *AnalysisResult, filepath string) (int, error) {
+	dec, err := OpenAudioDecoder(filepath)
+	if err != nil {
+		return 0, err
+	}
+	defer dec.Close()
 
-	result.DynamicRange = peakDB - rmsDB
-}
+	info := dec.Info()
+	if info.SampleRate == 0 || info.Channels == 0 {
+		return 0, fmt.Errorf("invalid stream info")
+	}
 
-func decodeFLACForMetrics(filepath string) ([]float64, error) {
-	stream, err := mewflac.ParseFile(filepath)
+	stft, err := newSTFT(info.SampleRate, SpectrogramOptions{})
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
-	defer stream.Close()
+	dr := newDRMeter(info.SampleRate, info.Channels)
+	usage := newBitUsage(info.BitsPerSample)
 
-	maxSamples := 10000000
-	samples := make([]float64, 0, maxSamples)
+	var peak, sumSquares float64
+	var count int64
 
 	for {
-		frame, err := stream.ParseNext()
-		if err != nil {
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
 			break
 		}
-
-		var channelSamples []int32
-		if len(frame.Subframes) > 0 {
-			channelSamples = frame.Subframes[0].Samples
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode frame: %w", err)
 		}
 
-		maxVal := float64(int64(1) << (stream.Info.BitsPerSample - 1))
-		for _, sample := range channelSamples {
-			if len(samples) >= maxSamples {
-				return samples, nil
+		stft.pushFrame(frame)
+		dr.push(frame)
+		usage.push(frame)
+
+		for _, sample := range frame[0] {
+			if abs := math.Abs(sample); abs > peak {
+				peak = abs
 			}
-			normalized := float64(sample) / maxVal
-			samples = append(samples, normalized)
+			sumSquares += sample * sample
 		}
+		count += int64(len(frame[0]))
+	}
 
-		if len(samples) >= maxSamples {
-			break
-		}
+	spectrum := stft.finish()
+	if len(spectrum.TimeSlices) == 0 {
+		return 0, fmt.Errorf("no audio samples found")
 	}
+	result.Spectrum = spectrum
 
-	return samples, nil
+	peakDB := 20.0 * math.Log10(peak)
+	rmsDB := 20.0 * math.Log10(math.Sqrt(sumSquares/float64(count)))
+	result.PeakAmplitude = peakDB
+	result.RMSLevel = rmsDB
+	result.DynamicRange = peakDB - rmsDB
+
+	if drResult, err := dr.result(); err != nil {
+		fmt.Printf("Warning: failed to measure dynamic range: %v\n", err)
+	} else {
+		result.DR = drResult
+		result.DynamicRange = float64(drResult.DR)
+	}
+
+	return usage.effectiveBits(), nil
 }
 
 func GetFileSize(filepath string) (int64, error) {
@@ -173,35 +166,15 @@ func GetTrackMetadata(filepath string) (*AnalysisResult, error) {
 		return nil, fmt.Errorf("failed to get file info: %w", err)
 	}
 
-	f, err := flac.ParseFile(filepath)
+	info, err := ProbeAudio(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
+		return nil, fmt.Errorf("failed to open audio file: %w", err)
 	}
 
 	result := &AnalysisResult{
 		FilePath: filepath,
 		FileSize: fileInfo.Size(),
 	}
-
-	if len(f.Meta) > 0 {
-		streamInfo := f.Meta[0]
-		if streamInfo.Type == flac.StreamInfo {
-			data := streamInfo.Data
-			if len(data) >= 18 {
-				result.SampleRate = uint32(data[10])<<12 | uint32(data[11])<<4 | uint32(data[12])>>4
-				result.BitsPerSample = ((data[12]&0x01)<<4 | data[13]>>4) + 1
-				result.TotalSamples = uint64(data[13]&0x0F)<<32 |
-					uint64(data[14])<<24 |
-					uint64(data[15])<<16 |
-					uint64(data[16])<<8 |
-					uint64(data[17])
-
-				if result.SampleRate > 0 {
-					result.Duration = float64(result.TotalSamples) / float64(result.SampleRate)
-				}
-			}
-		}
-	}
-	result.BitDepth = fmt.Sprintf("%d-bit", result.BitsPerSample)
+	applyAudioInfo(result, info)
 	return result, nil
 }
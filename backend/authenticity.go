@@ -0,0 +1,218 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	VerdictLossless       = "lossless"
+	VerdictLossyTranscode = "lossy_transcode"
+	VerdictUpsampled      = "upsampled"
+	VerdictPaddedBitDepth = "padded_bit_depth"
+
+	cutoffSearchStartHz = 10000.0
+	cutoffDropWindowHz  = 500.0
+	cutoffMinDropDB     = 25.0
+	shelfToleranceHz    = 600.0
+)
+
+var lossyShelvesHz = []float64{16000, 19000, 20000}
+
+type AuthenticityResult struct {
+	Verdict         string   `json:"verdict"`
+	Confidence      float64  `json:"confidence"`
+	CutoffFrequency float64  `json:"cutoff_frequency"`
+	CutoffDropDB    float64  `json:"cutoff_drop_db"`
+	LowpassShelf    float64  `json:"lowpass_shelf,omitempty"`
+	EffectiveBits   int      `json:"effective_bits"`
+	PaddedBitDepth  bool     `json:"padded_bit_depth"`
+	Reasons         []string `json:"reasons,omitempty"`
+}
+
+func DetectFakeLossless(spectrum *SpectrumData, bitsPerSample uint8, effectiveBits int) *AuthenticityResult {
+	result := &AuthenticityResult{
+		Verdict:       VerdictLossless,
+		EffectiveBits: int(bitsPerSample),
+	}
+
+	var lossyScore, upsampledScore, paddedScore float64
+
+	if spectrum != nil && len(spectrum.TimeSlices) > 0 {
+		cutoff, drop := estimateCutoff(spectrum)
+		result.CutoffFrequency = cutoff
+		result.CutoffDropDB = drop
+
+		if drop >= cutoffMinDropDB {
+			strength := math.Min(1, drop/(2*cutoffMinDropDB))
+
+			for _, shelf := range lossyShelvesHz {
+				if math.Abs(cutoff-shelf) <= shelfToleranceHz {
+					result.LowpassShelf = shelf
+					lossyScore = 0.5 + 0.5*strength
+					result.Reasons = append(result.Reasons, fmt.Sprintf("steep lowpass at %.0f Hz matches a %.0f kHz lossy encoder shelf", cutoff, shelf/1000))
+					break
+				}
+			}
+
+			if result.LowpassShelf == 0 && cutoff < spectrum.MaxFreq*0.8 {
+				lossyScore = 0.3 + 0.4*strength
+				result.Reasons = append(result.Reasons, fmt.Sprintf("steep lowpass at %.0f Hz well below Nyquist (%.0f Hz)", cutoff, spectrum.MaxFreq))
+			}
+
+			if spectrum.SampleRate > 48000 && cutoff <= 24500 {
+				upsampledScore = 0.5 + 0.5*strength
+				result.Reasons = append(result.Reasons, fmt.Sprintf("%d Hz file has no content above %.0f Hz", spectrum.SampleRate, cutoff))
+			}
+		}
+	}
+
+	if bitsPerSample > 16 && effectiveBits > 0 {
+		result.EffectiveBits = effectiveBits
+		if effectiveBits <= 16 {
+			result.PaddedBitDepth = true
+			paddedScore = 0.95
+			result.Reasons = append(result.Reasons, fmt.Sprintf("%d-bit file only uses the top %d bits", bitsPerSample, effectiveBits))
+		}
+	}
+
+	maxScore := lossyScore
+	result.Verdict = VerdictLossyTranscode
+	if upsampledScore > maxScore {
+		maxScore = upsampledScore
+		result.Verdict = VerdictUpsampled
+	}
+	if paddedScore > maxScore {
+		maxScore = paddedScore
+		result.Verdict = VerdictPaddedBitDepth
+	}
+
+	if maxScore < 0.5 {
+		result.Verdict = VerdictLossless
+		result.Confidence = 1 - maxScore
+	} else {
+		result.Confidence = maxScore
+	}
+
+	return result
+}
+
+func estimateCutoff(spectrum *SpectrumData) (float64, float64) {
+	bins := spectrum.FreqBins
+	binHz := spectrum.MaxFreq / float64(bins)
+
+	avg := make([]float64, bins)
+	for _, slice := range spectrum.TimeSlices {
+		for j := 0; j < bins && j < len(slice.Magnitudes); j++ {
+			avg[j] += slice.Magnitudes[j]
+		}
+	}
+	for j := range avg {
+		avg[j] /= float64(len(spectrum.TimeSlices))
+	}
+
+	smoothRadius := int(100 / binHz)
+	if smoothRadius < 1 {
+		smoothRadius = 1
+	}
+	smoothed := make([]float64, bins)
+	for j := range avg {
+		lo, hi := j-smoothRadius, j+smoothRadius
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= bins {
+			hi = bins - 1
+		}
+		var sum float64
+		for k := lo; k <= hi; k++ {
+			sum += avg[k]
+		}
+		smoothed[j] = sum / float64(hi-lo+1)
+	}
+
+	window := int(cutoffDropWindowHz / binHz)
+	start := int(cutoffSearchStartHz / binHz)
+	if window < 1 {
+		window = 1
+	}
+
+	bestDrop := 0.0
+	bestBin := -1
+	for j := start; j+window < bins && j-window >= 0; j++ {
+		drop := smoothed[j-window] - smoothed[j+window]
+		if drop > bestDrop {
+			bestDrop = drop
+			bestBin = j
+		}
+	}
+
+	if bestBin >= 0 && bestDrop >= cutoffMinDropDB {
+		mid := (smoothed[bestBin-window] + smoothed[bestBin+window]) / 2
+		edge := bestBin
+		for j := bestBin - window; j <= bestBin+window; j++ {
+			if smoothed[j] < mid {
+				edge = j
+				break
+			}
+		}
+		return float64(edge) * binHz, bestDrop
+	}
+
+	floor := math.Inf(1)
+	for _, v := range smoothed {
+		if v < floor {
+			floor = v
+		}
+	}
+
+	cutoffBin := bins - 1
+	for j := bins - 1; j >= 0; j-- {
+		if smoothed[j] > floor+10 {
+			cutoffBin = j
+			break
+		}
+	}
+
+	return float64(cutoffBin) * binHz, bestDrop
+}
+
+type bitUsage struct {
+	bits  int
+	scale float64
+	used  uint32
+}
+
+// Decoders hand out samples scaled to [-1, 1); up to 24 bits survive the
+// float32 PCM pipe exactly, so the integer sample can be recovered from them.
+func newBitUsage(bitsPerSample int) *bitUsage {
+	if bitsPerSample <= 16 || bitsPerSample > 24 {
+		return nil
+	}
+	return &bitUsage{
+		bits:  bitsPerSample,
+		scale: float64(int64(1) << (bitsPerSample - 1)),
+	}
+}
+
+func (b *bitUsage) push(frame [][]float64) {
+	if b == nil || b.used&1 != 0 {
+		return
+	}
+	for _, samples := range frame {
+		for _, sample := range samples {
+			b.used |= uint32(int32(math.Round(sample * b.scale)))
+		}
+	}
+}
+
+func (b *bitUsage) effectiveBits() int {
+	if b == nil {
+		return 0
+	}
+	if b.used == 0 {
+		return b.bits
+	}
+	return b.bits - bits.TrailingZeros32(b.used)
+}
@@ -0,0 +1,292 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	pathfilepath "path/filepath"
+	"strconv"
+	"strings"
+
+	mewflac "github.com/mewkiz/flac"
+)
+
+const pcmPipeFrameSize = 4096
+
+type AudioInfo struct {
+	SampleRate    int     `json:"sample_rate"`
+	Channels      int     `json:"channels"`
+	BitsPerSample int     `json:"bits_per_sample"`
+	TotalSamples  uint64  `json:"total_samples"`
+	Duration      float64 `json:"duration"`
+	Codec         string  `json:"codec"`
+}
+
+type AudioDecoder interface {
+	Info() AudioInfo
+	ReadFrame() ([][]float64, error)
+	Close() error
+}
+
+func OpenAudioDecoder(filepath string) (AudioDecoder, error) {
+	if strings.ToLower(pathfilepath.Ext(filepath)) == ".flac" {
+		dec, err := openFLACDecoder(filepath)
+		if err == nil {
+			return dec, nil
+		}
+		fmt.Printf("Native FLAC decoding failed, falling back to ffmpeg: %v\n", err)
+	}
+
+	return openFFmpegDecoder(filepath)
+}
+
+func ProbeAudio(filepath string) (AudioInfo, error) {
+	dec, err := OpenAudioDecoder(filepath)
+	if err != nil {
+		return AudioInfo{}, err
+	}
+	defer dec.Close()
+
+	return dec.Info(), nil
+}
+
+type flacDecoder struct {
+	stream *mewflac.Stream
+	info   AudioInfo
+	scale  float64
+	buf    [][]float64
+}
+
+func openFLACDecoder(filepath string) (*flacDecoder, error) {
+	stream, err := mewflac.ParseFile(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse FLAC: %w", err)
+	}
+
+	info := AudioInfo{
+		SampleRate:    int(stream.Info.SampleRate),
+		Channels:      int(stream.Info.NChannels),
+		BitsPerSample: int(stream.Info.BitsPerSample),
+		TotalSamples:  stream.Info.NSamples,
+		Codec:         "flac",
+	}
+	if info.SampleRate > 0 {
+		info.Duration = float64(info.TotalSamples) / float64(info.SampleRate)
+	}
+
+	return &flacDecoder{
+		stream: stream,
+		info:   info,
+		scale:  float64(int64(1) << (stream.Info.BitsPerSample - 1)),
+		buf:    make([][]float64, info.Channels),
+	}, nil
+}
+
+func (d *flacDecoder) Info() AudioInfo {
+	return d.info
+}
+
+func (d *flacDecoder) ReadFrame() ([][]float64, error) {
+	frame, err := d.stream.ParseNext()
+	if err != nil {
+		return nil, err
+	}
+
+	n := frame.Subframes[0].NSamples
+	for ch := range d.buf {
+		if cap(d.buf[ch]) < n {
+			d.buf[ch] = make([]float64, n)
+		}
+		d.buf[ch] = d.buf[ch][:n]
+		if ch >= len(frame.Subframes) {
+			continue
+		}
+		for i, sample := range frame.Subframes[ch].Samples[:n] {
+			d.buf[ch][i] = float64(sample) / d.scale
+		}
+	}
+
+	return d.buf, nil
+}
+
+func (d *flacDecoder) Close() error {
+	return d.stream.Close()
+}
+
+type ffmpegDecoder struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *bytes.Buffer
+	info   AudioInfo
+	raw    []byte
+	buf    [][]float64
+	done   bool
+}
+
+func openFFmpegDecoder(filepath string) (*ffmpegDecoder, error) {
+	info, err := probeWithFFprobe(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	ffmpegPath, err := GetFFmpegPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateExecutable(ffmpegPath); err != nil {
+		return nil, fmt.Errorf("invalid ffmpeg executable: %w", err)
+	}
+
+	cmd := exec.Command(ffmpegPath,
+		"-v", "error",
+		"-i", filepath,
+		"-map", "0:a:0",
+		"-f", "f32le",
+		"-acodec", "pcm_f32le",
+		"-",
+	)
+	setHideWindow(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ffmpeg output: %w", err)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	buf := make([][]float64, info.Channels)
+	for ch := range buf {
+		buf[ch] = make([]float64, 0, pcmPipeFrameSize)
+	}
+
+	return &ffmpegDecoder{
+		cmd:    cmd,
+		stdout: stdout,
+		stderr: stderr,
+		info:   info,
+		raw:    make([]byte, pcmPipeFrameSize*info.Channels*4),
+		buf:    buf,
+	}, nil
+}
+
+func (d *ffmpegDecoder) Info() AudioInfo {
+	return d.info
+}
+
+func (d *ffmpegDecoder) ReadFrame() ([][]float64, error) {
+	if d.done {
+		return nil, io.EOF
+	}
+
+	n, err := io.ReadFull(d.stdout, d.raw)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		d.done = true
+		if waitErr := d.cmd.Wait(); waitErr != nil {
+			return nil, fmt.Errorf("ffmpeg decoding failed: %s - %w", strings.TrimSpace(d.stderr.String()), waitErr)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read ffmpeg output: %w", err)
+	}
+
+	frameBytes := d.info.Channels * 4
+	samples := n / frameBytes
+	if samples == 0 {
+		return nil, io.EOF
+	}
+
+	for ch := range d.buf {
+		d.buf[ch] = d.buf[ch][:samples]
+	}
+	for i := 0; i < samples; i++ {
+		for ch := range d.buf {
+			offset := i*frameBytes + ch*4
+			d.buf[ch][i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(d.raw[offset:])))
+		}
+	}
+
+	return d.buf, nil
+}
+
+func (d *ffmpegDecoder) Close() error {
+	if d.done {
+		return nil
+	}
+	d.done = true
+	d.stdout.Close()
+	if d.cmd.Process != nil {
+		d.cmd.Process.Kill()
+	}
+	d.cmd.Wait()
+	return nil
+}
+
+func probeWithFFprobe(filepath string) (AudioInfo, error) {
+	var info AudioInfo
+
+	ffprobePath, err := GetFFprobePath()
+	if err != nil {
+		return info, err
+	}
+	if err := ValidateExecutable(ffprobePath); err != nil {
+		return info, fmt.Errorf("invalid ffprobe executable: %w", err)
+	}
+
+	cmd := exec.Command(ffprobePath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_streams",
+		"-select_streams", "a:0",
+		filepath,
+	)
+	setHideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return info, fmt.Errorf("ffprobe failed: %w", err)
+	}
+
+	var result struct {
+		Streams []struct {
+			CodecName        string `json:"codec_name"`
+			SampleRate       string `json:"sample_rate"`
+			Channels         int    `json:"channels"`
+			BitsPerSample    int    `json:"bits_per_sample"`
+			BitsPerRawSample string `json:"bits_per_raw_sample"`
+			Duration         string `json:"duration"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(output, &result); err != nil {
+		return info, err
+	}
+	if len(result.Streams) == 0 {
+		return info, fmt.Errorf("no audio stream found")
+	}
+
+	stream := result.Streams[0]
+	info.Codec = stream.CodecName
+	info.Channels = stream.Channels
+	info.SampleRate, _ = strconv.Atoi(stream.SampleRate)
+	info.BitsPerSample = stream.BitsPerSample
+	if raw, err := strconv.Atoi(stream.BitsPerRawSample); err == nil && raw > 0 {
+		info.BitsPerSample = raw
+	}
+	if duration, err := strconv.ParseFloat(stream.Duration, 64); err == nil {
+		info.Duration = duration
+		info.TotalSamples = uint64(math.Round(duration * float64(info.SampleRate)))
+	}
+
+	if info.SampleRate == 0 || info.Channels == 0 {
+		return info, fmt.Errorf("could not determine audio format")
+	}
+
+	return info, nil
+}
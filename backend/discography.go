@@ -0,0 +1,490 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	ReleaseTypeAlbum       = "album"
+	ReleaseTypeSingle      = "single"
+	ReleaseTypeCompilation = "compilation"
+
+	EditionRuleDeluxe   = "deluxe"
+	EditionRuleOriginal = "original"
+	EditionRuleLatest   = "latest"
+	EditionRuleKeepAll  = "all"
+
+	DiscographyActionDownload = "download"
+	DiscographyActionSkip     = "skip"
+
+	DiscographyReasonReleaseType = "release_type"
+	DiscographyReasonEdition     = "edition"
+	DiscographyReasonDuplicate   = "duplicate"
+	DiscographyReasonInLibrary   = "in_library"
+	DiscographyReasonUnavailable = "unavailable"
+
+	discographyDurationToleranceMS = 3000
+)
+
+var discographyEditionRegex = regexp.MustCompile(`(?i)\s*(?:[\(\[][^\)\]]*\b(?:deluxe|remaster(?:ed)?|expanded|anniversary|special edition|collector'?s edition|bonus tracks?|edition|reissue)\b[^\)\]]*[\)\]]|\s-\s[^-]*\b(?:deluxe|remaster(?:ed)?|expanded|anniversary|edition|reissue)\b.*$)`)
+
+type DiscographyPlanOptions struct {
+	ReleaseTypes    []string `json:"release_types,omitempty"`
+	EditionRule     string   `json:"edition_rule,omitempty"`
+	KeepBonusTracks bool     `json:"keep_bonus_tracks,omitempty"`
+}
+
+type DiscographyPlanRelease struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	ReleaseDate string `json:"release_date"`
+	TotalTracks int    `json:"total_tracks"`
+	Included    bool   `json:"included"`
+	Reason      string `json:"reason,omitempty"`
+	EditionOf   string `json:"edition_of,omitempty"`
+}
+
+type DiscographyPlanTrack struct {
+	Track        AlbumTrackMetadata `json:"track"`
+	ReleaseType  string             `json:"release_type"`
+	ISRC         string             `json:"isrc,omitempty"`
+	Action       string             `json:"action"`
+	Reason       string             `json:"reason,omitempty"`
+	DuplicateOf  string             `json:"duplicate_of,omitempty"`
+	MatchedBy    string             `json:"matched_by,omitempty"`
+	ExistingPath string             `json:"existing_path,omitempty"`
+}
+
+type DiscographyPlan struct {
+	ArtistID   string                   `json:"artist_id"`
+	ArtistName string                   `json:"artist_name"`
+	Options    DiscographyPlanOptions   `json:"options"`
+	Releases   []DiscographyPlanRelease `json:"releases"`
+	Tracks     []DiscographyPlanTrack   `json:"tracks"`
+	Download   int                      `json:"download"`
+	Skipped    int                      `json:"skipped"`
+	CreatedAt  int64                    `json:"created_at"`
+}
+
+type discographyRelease struct {
+	info   DiscographyPlanRelease
+	tracks []AlbumTrackMetadata
+}
+
+func discographyTypeRank(releaseType string) int {
+	switch releaseType {
+	case ReleaseTypeAlbum:
+		return 0
+	case ReleaseTypeCompilation:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func discographyBaseTitle(title string) string {
+	return strings.Join(normalizeForMatch(discographyEditionRegex.ReplaceAllString(title, "")), " ")
+}
+
+func discographyTrackKey(track AlbumTrackMetadata) string {
+	artist := track.Artists
+	if artist == "" {
+		artist = track.AlbumArtist
+	}
+	return libraryNameKey(artist, discographyEditionRegex.ReplaceAllString(track.Name, ""))
+}
+
+func discographyReleaseTypes(options DiscographyPlanOptions, group string) (map[string]bool, error) {
+	requested := options.ReleaseTypes
+	if len(requested) == 0 && group != "" {
+		requested = []string{group}
+	}
+
+	types := make(map[string]bool)
+	for _, t := range requested {
+		switch t = strings.ToLower(strings.TrimSpace(t)); t {
+		case "", "all":
+			types[ReleaseTypeAlbum] = true
+			types[ReleaseTypeSingle] = true
+			types[ReleaseTypeCompilation] = true
+		case ReleaseTypeAlbum, ReleaseTypeSingle, ReleaseTypeCompilation:
+			types[t] = true
+		default:
+			return nil, fmt.Errorf("unsupported release type: %s", t)
+		}
+	}
+	if len(types) == 0 {
+		types[ReleaseTypeAlbum] = true
+		types[ReleaseTypeSingle] = true
+		types[ReleaseTypeCompilation] = true
+	}
+	return types, nil
+}
+
+func preferredEdition(rule string, a, b *discographyRelease) bool {
+	aBase := discographyBaseTitle(a.info.Name) == strings.Join(normalizeForMatch(a.info.Name), " ")
+	bBase := discographyBaseTitle(b.info.Name) == strings.Join(normalizeForMatch(b.info.Name), " ")
+
+	switch rule {
+	case EditionRuleOriginal:
+		if a.info.ReleaseDate != b.info.ReleaseDate {
+			return a.info.ReleaseDate < b.info.ReleaseDate
+		}
+		if aBase != bBase {
+			return aBase
+		}
+		return len(a.tracks) < len(b.tracks)
+	case EditionRuleLatest:
+		if a.info.ReleaseDate != b.info.ReleaseDate {
+			return a.info.ReleaseDate > b.info.ReleaseDate
+		}
+		return len(a.tracks) > len(b.tracks)
+	default:
+		if len(a.tracks) != len(b.tracks) {
+			return len(a.tracks) > len(b.tracks)
+		}
+		return a.info.ReleaseDate > b.info.ReleaseDate
+	}
+}
+
+func collapseDiscographyEditions(releases []*discographyRelease, rule string) map[string]*discographyRelease {
+	keptBy := make(map[string]*discographyRelease)
+	if rule == EditionRuleKeepAll {
+		return keptBy
+	}
+
+	groups := make(map[string][]*discographyRelease)
+	var order []string
+	for _, release := range releases {
+		if !release.info.Included {
+			continue
+		}
+		key := release.info.Type + "\x00" + discographyBaseTitle(release.info.Name)
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], release)
+	}
+
+	for _, key := range order {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+
+		kept := group[0]
+		for _, candidate := range group[1:] {
+			if preferredEdition(rule, candidate, kept) {
+				kept = candidate
+			}
+		}
+
+		for _, release := range group {
+			if release == kept {
+				continue
+			}
+			release.info.Included = false
+			release.info.Reason = DiscographyReasonEdition
+			release.info.EditionOf = kept.info.ID
+			keptBy[release.info.ID] = kept
+		}
+	}
+
+	return keptBy
+}
+
+func planDiscography(ctx context.Context, releases []*discographyRelease, options DiscographyPlanOptions, resolveISRC func(spotifyID string) string) ([]DiscographyPlanTrack, error) {
+	keptBy := collapseDiscographyEditions(releases, options.EditionRule)
+
+	ordered := make([]*discographyRelease, 0, len(releases))
+	for _, release := range releases {
+		if release.info.Included || release.info.Reason == DiscographyReasonEdition {
+			ordered = append(ordered, release)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.info.Included != b.info.Included {
+			return a.info.Included
+		}
+		if ra, rb := discographyTypeRank(a.info.Type), discographyTypeRank(b.info.Type); ra != rb {
+			return ra < rb
+		}
+		return a.info.ReleaseDate < b.info.ReleaseDate
+	})
+
+	isrcCache := make(map[string]string)
+	trackISRC := func(spotifyID string) string {
+		if spotifyID == "" || resolveISRC == nil {
+			return ""
+		}
+		if isrc, ok := isrcCache[spotifyID]; ok {
+			return isrc
+		}
+		isrc := strings.ToUpper(strings.TrimSpace(resolveISRC(spotifyID)))
+		if !isValidISRC(isrc) {
+			isrc = ""
+		}
+		isrcCache[spotifyID] = isrc
+		return isrc
+	}
+
+	planned := make(map[string][]DiscographyPlanTrack)
+	byName := make(map[string][]*DiscographyPlanTrack)
+	byRelease := make(map[string]map[string]string)
+
+	for _, release := range ordered {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		names := make(map[string]string)
+		byRelease[release.info.ID] = names
+		tracks := make([]DiscographyPlanTrack, 0, len(release.tracks))
+
+		for _, track := range release.tracks {
+			entry := DiscographyPlanTrack{
+				Track:       track,
+				ReleaseType: release.info.Type,
+				Action:      DiscographyActionDownload,
+			}
+			key := discographyTrackKey(track)
+
+			if kept, ok := keptBy[release.info.ID]; ok {
+				if keptID, found := byRelease[kept.info.ID][key]; found && key != "" {
+					entry.Action = DiscographyActionSkip
+					entry.Reason = DiscographyReasonEdition
+					entry.DuplicateOf = keptID
+					entry.MatchedBy = "name"
+				} else if !options.KeepBonusTracks {
+					entry.Action = DiscographyActionSkip
+					entry.Reason = DiscographyReasonEdition
+				}
+			}
+
+			if entry.Action == DiscographyActionDownload && key != "" {
+				for _, other := range byName[key] {
+					if other.Track.AlbumID == track.AlbumID {
+						continue
+					}
+
+					if other.ISRC == "" {
+						other.ISRC = trackISRC(other.Track.SpotifyID)
+					}
+					if entry.ISRC == "" {
+						entry.ISRC = trackISRC(track.SpotifyID)
+					}
+
+					matchedBy := ""
+					if entry.ISRC != "" && other.ISRC != "" {
+						if entry.ISRC == other.ISRC {
+							matchedBy = "isrc"
+						}
+					} else {
+						diff := entry.Track.DurationMS - other.Track.DurationMS
+						if diff < 0 {
+							diff = -diff
+						}
+						if entry.Track.DurationMS == 0 || other.Track.DurationMS == 0 || diff <= discographyDurationToleranceMS {
+							matchedBy = "name"
+						}
+					}
+
+					if matchedBy != "" {
+						entry.Action = DiscographyActionSkip
+						entry.Reason = DiscographyReasonDuplicate
+						entry.DuplicateOf = other.Track.SpotifyID
+						entry.MatchedBy = matchedBy
+						break
+					}
+				}
+			}
+
+			if key != "" {
+				if _, ok := names[key]; !ok {
+					names[key] = track.SpotifyID
+				}
+			}
+			tracks = append(tracks, entry)
+		}
+
+		planned[release.info.ID] = tracks
+		for i := range tracks {
+			if tracks[i].Action == DiscographyActionDownload {
+				if key := discographyTrackKey(tracks[i].Track); key != "" {
+					byName[key] = append(byName[key], &tracks[i])
+				}
+			}
+		}
+	}
+
+	var result []DiscographyPlanTrack
+	for _, release := range releases {
+		result = append(result, planned[release.info.ID]...)
+	}
+	return result, nil
+}
+
+func resolveDiscographyISRC(songlink *SongLinkClient, spotifyID string) string {
+	if historyDB != nil {
+		var isrc string
+		historyDB.View(func(tx *bolt.Tx) error {
+			for _, track := range lookupLibraryKey(tx, "spotify:"+spotifyID) {
+				if isValidISRC(strings.ToUpper(track.ISRC)) {
+					isrc = track.ISRC
+					break
+				}
+			}
+			return nil
+		})
+		if isrc != "" {
+			return isrc
+		}
+	}
+
+	deezerURL, err := songlink.GetDeezerURLFromSpotify(spotifyID)
+	if err != nil {
+		fmt.Printf("[Discography] Could not resolve ISRC for %s: %v\n", spotifyID, err)
+		return ""
+	}
+	isrc, err := GetDeezerISRC(deezerURL)
+	if err != nil {
+		fmt.Printf("[Discography] Could not resolve ISRC for %s: %v\n", spotifyID, err)
+		return ""
+	}
+	return isrc
+}
+
+func PlanDiscographyDownload(ctx context.Context, artistURL string, options DiscographyPlanOptions) (*DiscographyPlan, error) {
+	parsed, err := parseSpotifyURI(artistURL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Type != "artist" && parsed.Type != "artist_discography" {
+		return nil, fmt.Errorf("only artist URLs can be planned, got %s", parsed.Type)
+	}
+
+	switch options.EditionRule {
+	case "":
+		options.EditionRule = EditionRuleDeluxe
+	case EditionRuleDeluxe, EditionRuleOriginal, EditionRuleLatest, EditionRuleKeepAll:
+	default:
+		return nil, fmt.Errorf("unsupported edition rule: %s", options.EditionRule)
+	}
+
+	types, err := discographyReleaseTypes(options, parsed.DiscographyGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	client := NewSpotifyMetadataClient()
+	raw, err := client.fetchArtistDiscography(ctx, spotifyURI{Type: "artist_discography", ID: parsed.ID, DiscographyGroup: "all"})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &DiscographyPlan{
+		ArtistID:   raw.ID,
+		ArtistName: raw.Name,
+		Options:    options,
+		CreatedAt:  time.Now().Unix(),
+	}
+
+	releases := make([]*discographyRelease, 0, len(raw.Discography.All))
+	for _, alb := range raw.Discography.All {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		releaseType := alb.Type
+		if releaseType == "" {
+			releaseType = ReleaseTypeAlbum
+		}
+
+		release := &discographyRelease{
+			info: DiscographyPlanRelease{
+				ID:          alb.ID,
+				Name:        alb.Name,
+				Type:        releaseType,
+				ReleaseDate: alb.Date,
+				Included:    types[releaseType],
+			},
+		}
+		releases = append(releases, release)
+
+		if !release.info.Included {
+			release.info.Reason = DiscographyReasonReleaseType
+			continue
+		}
+
+		albumData, err := client.fetchAlbum(ctx, alb.ID)
+		if err != nil {
+			fmt.Printf("[Discography] Error getting tracks for %s: %v\n", alb.Name, err)
+			release.info.Included = false
+			release.info.Reason = DiscographyReasonUnavailable
+			continue
+		}
+
+		payload, err := client.formatAlbumData(albumData)
+		if err != nil {
+			fmt.Printf("[Discography] Error getting tracks for %s: %v\n", alb.Name, err)
+			release.info.Included = false
+			release.info.Reason = DiscographyReasonUnavailable
+			continue
+		}
+
+		for i := range payload.TrackList {
+			payload.TrackList[i].AlbumType = releaseType
+		}
+		release.tracks = payload.TrackList
+		release.info.TotalTracks = len(payload.TrackList)
+	}
+
+	songlink := NewSongLinkClient()
+	plan.Tracks, err = planDiscography(ctx, releases, options, func(spotifyID string) string {
+		return resolveDiscographyISRC(songlink, spotifyID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range plan.Tracks {
+		entry := &plan.Tracks[i]
+		if entry.Action == DiscographyActionDownload {
+			match, err := FindLibraryTrack(LibraryQuery{
+				SpotifyID: entry.Track.SpotifyID,
+				ISRC:      entry.ISRC,
+				Title:     entry.Track.Name,
+				Artist:    entry.Track.Artists,
+				Duration:  entry.Track.DurationMS / 1000,
+			})
+			if err == nil && match != nil {
+				entry.Action = DiscographyActionSkip
+				entry.Reason = DiscographyReasonInLibrary
+				entry.MatchedBy = match.MatchedBy
+				entry.ExistingPath = match.Track.Path
+			}
+		}
+
+		if entry.Action == DiscographyActionDownload {
+			plan.Download++
+		} else {
+			plan.Skipped++
+		}
+	}
+
+	for _, release := range releases {
+		plan.Releases = append(plan.Releases, release.info)
+	}
+
+	fmt.Printf("[Discography] Planned %s: %d releases, %d tracks to download, %d skipped\n", plan.ArtistName, len(plan.Releases), plan.Download, plan.Skipped)
+	return plan, nil
+}
@@ -0,0 +1,226 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+const (
+	drBlockSeconds  = 3
+	drTopBlockRatio = 0.2
+)
+
+type ChannelDR struct {
+	Channel int     `json:"channel"`
+	DR      float64 `json:"dr"`
+	PeakDB  float64 `json:"peak_db"`
+	RMSDB   float64 `json:"rms_db"`
+}
+
+type DRResult struct {
+	DR       int         `json:"dr"`
+	DRExact  float64     `json:"dr_exact"`
+	PeakDB   float64     `json:"peak_db"`
+	RMSDB    float64     `json:"rms_db"`
+	Channels []ChannelDR `json:"channels"`
+}
+
+type AlbumDRTrack struct {
+	FilePath string  `json:"file_path"`
+	DR       int     `json:"dr"`
+	PeakDB   float64 `json:"peak_db"`
+	RMSDB    float64 `json:"rms_db"`
+	Duration float64 `json:"duration"`
+}
+
+type AlbumDRReport struct {
+	DR      int            `json:"dr"`
+	DRExact float64        `json:"dr_exact"`
+	Tracks  []AlbumDRTrack `json:"tracks"`
+}
+
+type drChannelState struct {
+	blockPeaks []float64
+	blockRMS   []float64
+	peak       float64
+	sumSquares float64
+
+	curPeak float64
+	curSum  float64
+}
+
+func (s *drChannelState) closeBlock(n int) {
+	if n == 0 {
+		return
+	}
+	s.blockPeaks = append(s.blockPeaks, s.curPeak)
+	s.blockRMS = append(s.blockRMS, math.Sqrt(2*s.curSum/float64(n)))
+	s.curPeak = 0
+	s.curSum = 0
+}
+
+type drMeter struct {
+	states       []*drChannelState
+	blockLen     int
+	blockFill    int
+	totalSamples int64
+}
+
+func newDRMeter(sampleRate, channels int) *drMeter {
+	states := make([]*drChannelState, channels)
+	for ch := range states {
+		states[ch] = &drChannelState{}
+	}
+	return &drMeter{
+		states:   states,
+		blockLen: sampleRate * drBlockSeconds,
+	}
+}
+
+func (m *drMeter) push(frame [][]float64) {
+	n := len(frame[0])
+	for i := 0; i < n; i++ {
+		for ch, s := range m.states {
+			x := math.Abs(frame[ch][i])
+			if x > s.curPeak {
+				s.curPeak = x
+			}
+			if x > s.peak {
+				s.peak = x
+			}
+			s.curSum += x * x
+			s.sumSquares += x * x
+		}
+
+		m.blockFill++
+		if m.blockFill == m.blockLen {
+			for _, s := range m.states {
+				s.closeBlock(m.blockFill)
+			}
+			m.blockFill = 0
+		}
+	}
+	m.totalSamples += int64(n)
+}
+
+func (m *drMeter) result() (*DRResult, error) {
+	for _, s := range m.states {
+		s.closeBlock(m.blockFill)
+	}
+	m.blockFill = 0
+
+	if m.totalSamples == 0 {
+		return nil, fmt.Errorf("no audio samples decoded")
+	}
+
+	channels := len(m.states)
+	result := &DRResult{}
+	var drSum, peak, sumSquares float64
+	for ch, s := range m.states {
+		dr := channelDR(s)
+		result.Channels = append(result.Channels, ChannelDR{
+			Channel: ch,
+			DR:      dr,
+			PeakDB:  amplitudeToDB(s.peak),
+			RMSDB:   amplitudeToDB(math.Sqrt(s.sumSquares / float64(m.totalSamples))),
+		})
+		drSum += dr
+		sumSquares += s.sumSquares
+		if s.peak > peak {
+			peak = s.peak
+		}
+	}
+
+	result.DRExact = drSum / float64(channels)
+	result.DR = int(math.Round(result.DRExact))
+	result.PeakDB = amplitudeToDB(peak)
+	result.RMSDB = amplitudeToDB(math.Sqrt(sumSquares / float64(m.totalSamples*int64(channels))))
+
+	return result, nil
+}
+
+func MeasureDR(filepath string) (*DRResult, error) {
+	dec, err := OpenAudioDecoder(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	info := dec.Info()
+	if info.SampleRate == 0 || info.Channels == 0 {
+		return nil, fmt.Errorf("invalid stream info")
+	}
+
+	meter := newDRMeter(info.SampleRate, info.Channels)
+	for {
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode frame: %w", err)
+		}
+		meter.push(frame)
+	}
+
+	return meter.result()
+}
+
+func channelDR(s *drChannelState) float64 {
+	if len(s.blockRMS) == 0 {
+		return 0
+	}
+
+	rms := append([]float64(nil), s.blockRMS...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(rms)))
+
+	top := int(float64(len(rms)) * drTopBlockRatio)
+	if top < 1 {
+		top = 1
+	}
+	var sum float64
+	for _, r := range rms[:top] {
+		sum += r * r
+	}
+	topRMS := math.Sqrt(sum / float64(top))
+
+	peaks := append([]float64(nil), s.blockPeaks...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(peaks)))
+	peak := peaks[0]
+	if len(peaks) > 1 {
+		peak = peaks[1]
+	}
+
+	if topRMS <= 0 || peak <= 0 {
+		return 0
+	}
+	return 20 * math.Log10(peak/topRMS)
+}
+
+func BuildAlbumDRReport(results []*AnalysisResult) *AlbumDRReport {
+	report := &AlbumDRReport{}
+
+	var sum float64
+	for _, r := range results {
+		if r == nil || r.DR == nil {
+			continue
+		}
+		report.Tracks = append(report.Tracks, AlbumDRTrack{
+			FilePath: r.FilePath,
+			DR:       r.DR.DR,
+			PeakDB:   r.DR.PeakDB,
+			RMSDB:    r.DR.RMSDB,
+			Duration: r.Duration,
+		})
+		sum += r.DR.DRExact
+	}
+
+	if len(report.Tracks) > 0 {
+		report.DRExact = sum / float64(len(report.Tracks))
+		report.DR = int(math.Round(report.DRExact))
+	}
+
+	return report
+}
@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	EventItemStarted  = "download:item-started"
+	EventItemProgress = "download:item-progress"
+	EventItemFinished = "download:item-finished"
+	EventQueueChanged = "download:queue-changed"
+
+	EventLibraryUpdated = "library:updated"
+
+	progressEventInterval = 250 * time.Millisecond
+)
+
+type DownloadEvent struct {
+	Name    string      `json:"name"`
+	Payload interface{} `json:"payload"`
+}
+
+type ItemProgressEvent struct {
+	ID       string  `json:"id"`
+	Progress float64 `json:"progress"`
+	Speed    float64 `json:"speed"`
+}
+
+type QueueChangedEvent struct {
+	Total          int `json:"total"`
+	QueuedCount    int `json:"queued_count"`
+	ActiveCount    int `json:"active_count"`
+	CompletedCount int `json:"completed_count"`
+	FailedCount    int `json:"failed_count"`
+	SkippedCount   int `json:"skipped_count"`
+	SuspectCount   int `json:"suspect_count"`
+}
+
+type DownloadEventHandler func(DownloadEvent)
+
+var (
+	eventSubscribers     = make(map[int]DownloadEventHandler)
+	nextSubscriberID     int
+	eventSubscribersLock sync.RWMutex
+
+	lastProgressEvent     = make(map[string]time.Time)
+	lastProgressEventLock sync.Mutex
+)
+
+func SubscribeDownloadEvents(handler DownloadEventHandler) func() {
+	eventSubscribersLock.Lock()
+	id := nextSubscriberID
+	nextSubscriberID++
+	eventSubscribers[id] = handler
+	eventSubscribersLock.Unlock()
+
+	return func() {
+		eventSubscribersLock.Lock()
+		delete(eventSubscribers, id)
+		eventSubscribersLock.Unlock()
+	}
+}
+
+func emitDownloadEvent(name string, payload interface{}) {
+	eventSubscribersLock.RLock()
+	handlers := make([]DownloadEventHandler, 0, len(eventSubscribers))
+	for _, h := range eventSubscribers {
+		handlers = append(handlers, h)
+	}
+	eventSubscribersLock.RUnlock()
+
+	event := DownloadEvent{Name: name, Payload: payload}
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+func emitItemProgress(id string, progress, speed float64) {
+	now := time.Now()
+
+	lastProgressEventLock.Lock()
+	if last, ok := lastProgressEvent[id]; ok && now.Sub(last) < progressEventInterval {
+		lastProgressEventLock.Unlock()
+		return
+	}
+	lastProgressEvent[id] = now
+	lastProgressEventLock.Unlock()
+
+	emitDownloadEvent(EventItemProgress, ItemProgressEvent{
+		ID:       id,
+		Progress: progress,
+		Speed:    speed,
+	})
+}
+
+func emitItemFinished(item DownloadItem) {
+	lastProgressEventLock.Lock()
+	delete(lastProgressEvent, item.ID)
+	lastProgressEventLock.Unlock()
+
+	emitDownloadEvent(EventItemFinished, item)
+}
+
+func emitQueueChanged() {
+	emitDownloadEvent(EventQueueChanged, queueSummary())
+}
+
+func queueSummary() QueueChangedEvent {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	summary := QueueChangedEvent{Total: len(downloadQueue)}
+	for _, item := range downloadQueue {
+		switch item.Status {
+		case StatusQueued:
+			summary.QueuedCount++
+		case StatusDownloading:
+			summary.ActiveCount++
+		case StatusCompleted:
+			summary.CompletedCount++
+		case StatusFailed:
+			summary.FailedCount++
+		case StatusSkipped:
+			summary.SkippedCount++
+		case StatusSuspect:
+			summary.SuspectCount++
+		}
+	}
+	return summary
+}
+
+func WaitForDownloadItems(ctx context.Context, ids []string) map[string]DownloadItem {
+	changed := make(chan struct{}, 1)
+	unsubscribe := SubscribeDownloadEvents(func(event DownloadEvent) {
+		if event.Name == EventItemFinished || event.Name == EventQueueChanged {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer unsubscribe()
+
+	finished := make(map[string]DownloadItem, len(ids))
+	for {
+		for _, id := range ids {
+			if _, ok := finished[id]; ok {
+				continue
+			}
+			item, ok := GetDownloadItem(id)
+			switch {
+			case !ok:
+				finished[id] = DownloadItem{ID: id, Status: StatusFailed, ErrorMessage: "Removed from queue"}
+			case item.Status != StatusQueued && item.Status != StatusDownloading:
+				finished[id] = item
+			}
+		}
+		if len(finished) == len(ids) {
+			return finished
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			for _, id := range ids {
+				if _, ok := finished[id]; !ok {
+					finished[id] = DownloadItem{ID: id, Status: StatusFailed, ErrorMessage: ctx.Err().Error()}
+				}
+			}
+			return finished
+		}
+	}
+}
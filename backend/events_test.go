@@ -0,0 +1,155 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []DownloadEvent
+}
+
+func (r *eventRecorder) handle(event DownloadEvent) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *eventRecorder) names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.events))
+	for _, event := range r.events {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+func (r *eventRecorder) progress() []ItemProgressEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var progress []ItemProgressEvent
+	for _, event := range r.events {
+		if p, ok := event.Payload.(ItemProgressEvent); ok {
+			progress = append(progress, p)
+		}
+	}
+	return progress
+}
+
+func TestDownloadEventsLifecycle(t *testing.T) {
+	ClearAllDownloads()
+	t.Cleanup(ClearAllDownloads)
+
+	recorder := &eventRecorder{}
+	unsubscribe := SubscribeDownloadEvents(recorder.handle)
+	defer unsubscribe()
+
+	const id = "events-test-item"
+	AddToQueue(id, "Track", "Artist", "Album", "")
+	StartDownloadItem(id)
+
+	UpdateItemProgress(id, 1, 2)
+	UpdateItemProgress(id, 2, 2)
+	UpdateItemProgress(id, 3, 2)
+
+	time.Sleep(progressEventInterval + 50*time.Millisecond)
+	UpdateItemProgress(id, 4, 3)
+
+	CompleteDownloadItem(id, "/tmp/track.flac", 5)
+
+	want := []string{
+		EventQueueChanged,
+		EventItemStarted,
+		EventQueueChanged,
+		EventItemProgress,
+		EventItemProgress,
+		EventItemFinished,
+		EventQueueChanged,
+	}
+	got := recorder.names()
+	if len(got) != len(want) {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("event %d: got %s, want %s (all: %v)", i, got[i], want[i], got)
+		}
+	}
+
+	progress := recorder.progress()
+	if progress[0].Progress != 1 || progress[1].Progress != 4 {
+		t.Errorf("throttled progress = %+v, want updates 1 and 4 only", progress)
+	}
+
+	finished, ok := recorder.events[5].Payload.(DownloadItem)
+	if !ok || finished.Status != StatusCompleted || finished.FilePath != "/tmp/track.flac" {
+		t.Errorf("finished payload = %+v", recorder.events[5].Payload)
+	}
+
+	summary, ok := recorder.events[6].Payload.(QueueChangedEvent)
+	if !ok || summary.CompletedCount != 1 || summary.ActiveCount != 0 {
+		t.Errorf("final queue summary = %+v", recorder.events[6].Payload)
+	}
+}
+
+func TestDownloadEventsUnsubscribe(t *testing.T) {
+	ClearAllDownloads()
+	t.Cleanup(ClearAllDownloads)
+
+	first := &eventRecorder{}
+	second := &eventRecorder{}
+	unsubscribeFirst := SubscribeDownloadEvents(first.handle)
+	unsubscribeSecond := SubscribeDownloadEvents(second.handle)
+	defer unsubscribeSecond()
+
+	AddToQueue("unsubscribe-a", "Track", "Artist", "Album", "")
+	unsubscribeFirst()
+	AddToQueue("unsubscribe-b", "Track", "Artist", "Album", "")
+
+	if got := len(first.names()); got != 1 {
+		t.Errorf("unsubscribed handler received %d events, want 1", got)
+	}
+	if got := len(second.names()); got != 2 {
+		t.Errorf("subscribed handler received %d events, want 2", got)
+	}
+
+	unsubscribeFirst()
+}
+
+func TestWaitForDownloadItems(t *testing.T) {
+	ClearAllDownloads()
+	t.Cleanup(ClearAllDownloads)
+
+	for _, id := range []string{"wait-done", "wait-cancelled"} {
+		AddToQueue(id, "Track", "Artist", "Album", "")
+	}
+
+	go func() {
+		StartDownloadItem("wait-done")
+		CompleteDownloadItem("wait-done", "/tmp/done.flac", 1)
+		CancelAllQueuedItems()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	items := WaitForDownloadItems(ctx, []string{"wait-done", "wait-cancelled", "wait-missing"})
+	if ctx.Err() != nil {
+		t.Fatal("timed out waiting for items to finish")
+	}
+	if item := items["wait-done"]; item.Status != StatusCompleted || item.FilePath != "/tmp/done.flac" {
+		t.Errorf("completed item = %+v", item)
+	}
+	if item := items["wait-cancelled"]; item.Status != StatusSkipped {
+		t.Errorf("cancelled item = %+v", item)
+	}
+	if item := items["wait-missing"]; item.Status != StatusFailed {
+		t.Errorf("missing item = %+v", item)
+	}
+}
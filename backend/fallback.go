@@ -0,0 +1,161 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+type FallbackStep struct {
+	Service    string `json:"service"`
+	Quality    string `json:"quality,omitempty"`
+	MinQuality string `json:"min_quality,omitempty"`
+	ServiceURL string `json:"service_url,omitempty"`
+}
+
+type SkippedProvider struct {
+	Service string `json:"service"`
+	Quality string `json:"quality,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+type FallbackResult struct {
+	Filename string
+	Service  string
+	Quality  string
+	Skipped  []SkippedProvider
+}
+
+func qualityRank(provider Provider, quality string) int {
+	for i, q := range provider.Qualities() {
+		if q == quality {
+			return i
+		}
+	}
+	return -1
+}
+
+func DownloadWithFallback(chain []FallbackStep, req ProviderRequest) (FallbackResult, error) {
+	var result FallbackResult
+
+	if len(chain) == 0 {
+		return result, fmt.Errorf("fallback chain is empty")
+	}
+
+	for _, step := range chain {
+		skip := func(quality, reason string) {
+			fmt.Printf("Skipping %s: %s\n", step.Service, reason)
+			result.Skipped = append(result.Skipped, SkippedProvider{
+				Service: step.Service,
+				Quality: quality,
+				Reason:  reason,
+			})
+		}
+
+		provider, err := GetProvider(step.Service)
+		if err != nil {
+			skip("", err.Error())
+			continue
+		}
+
+		quality := step.Quality
+		if quality == "" {
+			quality = req.Quality
+		}
+		if qualityRank(provider, quality) < 0 {
+			qualities := provider.Qualities()
+			if len(qualities) == 0 {
+				skip(quality, "provider reports no qualities")
+				continue
+			}
+			quality = qualities[0]
+		}
+
+		floorRank := -1
+		if step.MinQuality != "" {
+			floorRank = qualityRank(provider, step.MinQuality)
+			if floorRank < 0 {
+				skip(quality, fmt.Sprintf("unknown quality floor %s", step.MinQuality))
+				continue
+			}
+			if qualityRank(provider, quality) < floorRank {
+				quality = step.MinQuality
+			}
+		}
+
+		stepReq := req
+		stepReq.Quality = quality
+		stepReq.ServiceURL = step.ServiceURL
+
+		release := func() {}
+		if req.AcquireProvider != nil {
+			release = req.AcquireProvider(provider.Name())
+		}
+
+		fmt.Printf("Trying %s (%s)...\n", provider.Name(), quality)
+		filename, quality, reason := fetchFromProvider(provider, stepReq, step.MinQuality, floorRank)
+		release()
+
+		if reason != "" {
+			skip(quality, reason)
+			continue
+		}
+
+		result.Filename = filename
+		result.Service = provider.Name()
+		result.Quality = quality
+		return result, nil
+	}
+
+	reasons := make([]string, 0, len(result.Skipped))
+	for _, s := range result.Skipped {
+		reasons = append(reasons, fmt.Sprintf("%s: %s", s.Service, s.Reason))
+	}
+	return result, fmt.Errorf("all providers failed (%s)", strings.Join(reasons, "; "))
+}
+
+func fetchFromProvider(provider Provider, req ProviderRequest, minQuality string, floorRank int) (string, string, string) {
+	quality := req.Quality
+
+	trackRef, err := provider.ResolveTrack(req)
+	if err != nil {
+		return "", quality, fmt.Sprintf("failed to resolve track: %v", err)
+	}
+
+	if floorRank >= 0 {
+		best, err := provider.ProbeQuality(trackRef)
+		if err != nil {
+			return "", quality, fmt.Sprintf("failed to probe quality: %v", err)
+		}
+
+		bestRank := qualityRank(provider, best)
+		if bestRank < floorRank {
+			return "", quality, fmt.Sprintf("best available quality %s is below floor %s", best, minQuality)
+		}
+		if bestRank < qualityRank(provider, quality) {
+			req.Quality = best
+			quality = best
+		}
+	}
+
+	filename, err := provider.FetchTrack(trackRef, req)
+	if err != nil {
+		removePartialDownload(filename)
+		return "", quality, fmt.Sprintf("download failed: %v", err)
+	}
+
+	return filename, quality, ""
+}
+
+func removePartialDownload(filename string) {
+	if filename == "" || strings.HasPrefix(filename, "EXISTS:") {
+		return
+	}
+
+	if _, err := os.Stat(filename); err == nil {
+		fmt.Printf("Removing corrupted/partial file after failed download: %s\n", filename)
+		if err := os.Remove(filename); err != nil {
+			fmt.Printf("Warning: Failed to remove corrupted file %s: %v\n", filename, err)
+		}
+	}
+}
@@ -148,11 +148,6 @@ func IsFFmpegInstalled() (bool, error) {
 
 func DownloadFFmpeg(progressCallback func(int)) error {
 
-	SetDownloadProgress(0)
-	SetDownloadSpeed(0)
-	SetDownloading(true)
-	defer SetDownloading(false)
-
 	ffmpegDir, err := GetFFmpegDir()
 	if err != nil {
 		return err
@@ -274,11 +269,6 @@ func downloadAndExtract(url, destDir string, progressCallback func(int), progres
 				lastBytes = downloaded
 			}
 
-			SetDownloadProgress(mbDownloaded)
-			if speedMBps > 0 {
-				SetDownloadSpeed(speedMBps)
-			}
-
 			if totalSize > 0 && progressCallback != nil {
 				rawProgress := float64(downloaded) / float64(totalSize)
 				scaledProgress := progressStart + int(rawProgress*float64(progressEnd-progressStart))
@@ -644,6 +634,10 @@ func ConvertAudio(req ConvertAudioRequest) ([]ConvertAudioResult, error) {
 				os.Remove(coverArtPath)
 			}
 
+			if err := IndexLibraryFile(outputFile); err != nil {
+				fmt.Printf("[Library] Failed to index converted file: %v\n", err)
+			}
+
 			result.Success = true
 			fmt.Printf("[FFmpeg] Successfully converted: %s\n", outputFile)
 
@@ -1,17 +1,10 @@
 package backend
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strconv"
 	"strings"
-
-	id3v2 "github.com/bogem/id3v2/v2"
-	"github.com/go-flac/flacvorbis"
-	"github.com/go-flac/go-flac"
 )
 
 type FileInfo struct {
@@ -30,6 +23,11 @@ type AudioMetadata struct {
 	TrackNumber int    `json:"track_number"`
 	DiscNumber  int    `json:"disc_number"`
 	Year        string `json:"year"`
+	ISRC        string `json:"isrc,omitempty"`
+	SpotifyURL  string `json:"spotify_url,omitempty"`
+	SpotifyID   string `json:"spotify_id,omitempty"`
+	TidalID     string `json:"tidal_id,omitempty"`
+	QobuzID     string `json:"qobuz_id,omitempty"`
 }
 
 type RenamePreview struct {
@@ -118,201 +116,35 @@ func ReadAudioMetadata(filePath string) (*AudioMetadata, error) {
 		return nil, fmt.Errorf("file does not exist")
 	}
 
-	ext := strings.ToLower(filepath.Ext(filePath))
-
-	switch ext {
-	case ".flac":
-		return readFlacMetadata(filePath)
-	case ".mp3":
-		return readMp3Metadata(filePath)
-	case ".m4a":
-		return readM4aMetadata(filePath)
-	default:
-		return nil, fmt.Errorf("unsupported file format: %s", ext)
-	}
-}
-
-func readFlacMetadata(filePath string) (*AudioMetadata, error) {
-	f, err := flac.ParseFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC file: %w", err)
-	}
-
-	metadata := &AudioMetadata{}
-
-	for _, block := range f.Meta {
-		if block.Type == flac.VorbisComment {
-			cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
-			if err != nil {
-				continue
-			}
-
-			for _, comment := range cmt.Comments {
-				parts := strings.SplitN(comment, "=", 2)
-				if len(parts) != 2 {
-					continue
-				}
-
-				fieldName := strings.ToUpper(parts[0])
-				value := parts[1]
-
-				switch fieldName {
-				case "TITLE":
-					metadata.Title = value
-				case "ARTIST":
-					metadata.Artist = value
-				case "ALBUM":
-					metadata.Album = value
-				case "ALBUMARTIST":
-					metadata.AlbumArtist = value
-				case "TRACKNUMBER":
-					if num, err := strconv.Atoi(value); err == nil {
-						metadata.TrackNumber = num
-					}
-				case "DISCNUMBER":
-					if num, err := strconv.Atoi(value); err == nil {
-						metadata.DiscNumber = num
-					}
-				case "DATE", "YEAR":
-					metadata.Year = value
-				}
-			}
-		}
-	}
-
-	return metadata, nil
-}
-
-func readMp3Metadata(filePath string) (*AudioMetadata, error) {
-	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
-	if err != nil {
-		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
-	}
-	defer tag.Close()
-
-	metadata := &AudioMetadata{
-		Title:  tag.Title(),
-		Artist: tag.Artist(),
-		Album:  tag.Album(),
-		Year:   tag.Year(),
-	}
-
-	if frames := tag.GetFrames("TPE2"); len(frames) > 0 {
-		if textFrame, ok := frames[0].(id3v2.TextFrame); ok {
-			metadata.AlbumArtist = textFrame.Text
-		}
-	}
-
-	if frames := tag.GetFrames(tag.CommonID("Track number/Position in set")); len(frames) > 0 {
-		if textFrame, ok := frames[0].(id3v2.TextFrame); ok {
-			trackStr := strings.Split(textFrame.Text, "/")[0]
-			if num, err := strconv.Atoi(trackStr); err == nil {
-				metadata.TrackNumber = num
-			}
-		}
-	}
-
-	if frames := tag.GetFrames(tag.CommonID("Part of a set")); len(frames) > 0 {
-		if textFrame, ok := frames[0].(id3v2.TextFrame); ok {
-			discStr := strings.Split(textFrame.Text, "/")[0]
-			if num, err := strconv.Atoi(discStr); err == nil {
-				metadata.DiscNumber = num
-			}
-		}
-	}
-
-	return metadata, nil
-}
-
-func readMetadataWithFFprobe(filePath string) (*AudioMetadata, error) {
-	ffprobePath, err := GetFFprobePath()
-	if err != nil {
-		return nil, err
-	}
-
-	if err := ValidateExecutable(ffprobePath); err != nil {
-		return nil, fmt.Errorf("invalid ffprobe executable: %w", err)
-	}
-
-	cmd := exec.Command(ffprobePath,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-		filePath,
-	)
-
-	setHideWindow(cmd)
-
-	output, err := cmd.Output()
+	tags, err := ReadTags(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var result struct {
-		Format struct {
-			Tags map[string]string `json:"tags"`
-		} `json:"format"`
-		Streams []struct {
-			Tags map[string]string `json:"tags"`
-		} `json:"streams"`
+	spotifyURL := ""
+	if strings.Contains(tags.URL, "open.spotify.com/track/") {
+		spotifyURL = tags.URL
 	}
 
-	if err := json.Unmarshal(output, &result); err != nil {
-		return nil, err
+	spotifyID := tags.SpotifyID
+	if spotifyID == "" && spotifyURL != "" {
+		spotifyID = strings.SplitN(spotifyURL[strings.LastIndex(spotifyURL, "/")+1:], "?", 2)[0]
 	}
 
-	metadata := &AudioMetadata{}
-
-	allTags := make(map[string]string)
-
-	for _, stream := range result.Streams {
-		for key, value := range stream.Tags {
-			allTags[strings.ToLower(key)] = value
-		}
-	}
-
-	for key, value := range result.Format.Tags {
-		allTags[strings.ToLower(key)] = value
-	}
-
-	for key, value := range allTags {
-		switch key {
-		case "title":
-			metadata.Title = value
-		case "artist":
-			metadata.Artist = value
-		case "album":
-			metadata.Album = value
-		case "album_artist", "albumartist":
-			metadata.AlbumArtist = value
-		case "track":
-
-			trackStr := strings.Split(value, "/")[0]
-			if num, err := strconv.Atoi(trackStr); err == nil {
-				metadata.TrackNumber = num
-			}
-		case "disc":
-			discStr := strings.Split(value, "/")[0]
-			if num, err := strconv.Atoi(discStr); err == nil {
-				metadata.DiscNumber = num
-			}
-		case "date", "year":
-			if metadata.Year == "" || len(value) > len(metadata.Year) {
-				metadata.Year = value
-			}
-		}
-	}
-
-	return metadata, nil
-}
-
-func readM4aMetadata(filePath string) (*AudioMetadata, error) {
-	metadata, err := readMetadataWithFFprobe(filePath)
-	if err != nil {
-		return &AudioMetadata{}, nil
-	}
-	return metadata, nil
+	return &AudioMetadata{
+		Title:       tags.Title,
+		Artist:      tags.Artist,
+		Album:       tags.Album,
+		AlbumArtist: tags.AlbumArtist,
+		TrackNumber: tags.TrackNumber,
+		DiscNumber:  tags.DiscNumber,
+		Year:        tags.Date,
+		ISRC:        tags.ISRC,
+		SpotifyURL:  spotifyURL,
+		SpotifyID:   spotifyID,
+		TidalID:     tags.TidalTrackID,
+		QobuzID:     tags.QobuzTrackID,
+	}, nil
 }
 
 func GenerateFilename(metadata *AudioMetadata, format string, ext string) string {
@@ -459,6 +291,10 @@ func RenameFiles(files []string, format string) []RenameResult {
 			continue
 		}
 
+		if err := MoveLibraryFile(filePath, newPath); err != nil {
+			fmt.Printf("[Library] Failed to update renamed file: %v\n", err)
+		}
+
 		result.Success = true
 		results = append(results, result)
 	}
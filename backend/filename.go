@@ -9,12 +9,17 @@ import (
 	"unicode/utf8"
 )
 
-func BuildExpectedFilename(trackName, artistName, albumName, albumArtist, releaseDate, filenameFormat string, includeTrackNumber bool, position, discNumber int, useAlbumTrackNumber bool) string {
+func BuildExpectedFilename(req TrackRequest) string {
 
-	safeTitle := sanitizeFilename(trackName)
-	safeArtist := sanitizeFilename(artistName)
-	safeAlbum := sanitizeFilename(albumName)
-	safeAlbumArtist := sanitizeFilename(albumArtist)
+	safeTitle := sanitizeFilename(req.Tags.Title)
+	safeArtist := sanitizeFilename(req.Tags.Artist)
+	safeAlbum := sanitizeFilename(req.Tags.Album)
+	safeAlbumArtist := sanitizeFilename(req.Tags.AlbumArtist)
+
+	releaseDate := req.Tags.ReleaseDate
+	filenameFormat := req.FilenameFormat
+	position := req.Position
+	discNumber := req.Tags.DiscNumber
 
 	year := ""
 	if len(releaseDate) >= 4 {
@@ -56,7 +61,7 @@ func BuildExpectedFilename(trackName, artistName, albumName, albumArtist, releas
 			filename = fmt.Sprintf("%s - %s", safeTitle, safeArtist)
 		}
 
-		if includeTrackNumber && position > 0 {
+		if req.IncludeTrackNumber && position > 0 {
 			filename = fmt.Sprintf("%02d. %s", position, filename)
 		}
 	}
@@ -50,11 +50,11 @@ func SelectFolderDialog(ctx context.Context, defaultPath string) (string, error)
 
 func SelectFileDialog(ctx context.Context) (string, error) {
 	options := wailsRuntime.OpenDialogOptions{
-		Title: "Select FLAC File for Analysis",
+		Title: "Select Audio File for Analysis",
 		Filters: []wailsRuntime.FileFilter{
 			{
-				DisplayName: "FLAC Audio Files (*.flac)",
-				Pattern:     "*.flac",
+				DisplayName: "Audio Files (*.flac, *.mp3, *.m4a, *.opus)",
+				Pattern:     "*.flac;*.mp3;*.m4a;*.opus",
 			},
 			{
 				DisplayName: "All Files (*.*)",
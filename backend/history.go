@@ -49,7 +49,10 @@ func InitHistoryDB(appName string) error {
 	}
 
 	err = db.Update(func(tx *bolt.Tx) error {
-		_, err := tx.CreateBucketIfNotExists([]byte(historyBucket))
+		if _, err := tx.CreateBucketIfNotExists([]byte(historyBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists([]byte(queueBucket))
 		return err
 	})
 
@@ -0,0 +1,631 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	libraryTracksBucket = "LibraryTracks"
+	libraryIndexBucket  = "LibraryIndex"
+	libraryRootsBucket  = "LibraryRoots"
+
+	libraryDurationTolerance = 3.0
+	libraryMaxScanErrors     = 50
+)
+
+var libraryFeatRegex = regexp.MustCompile(`(?i)\s*[\(\[][^\)\]]*\b(feat|ft|with)\b[^\)\]]*[\)\]]`)
+
+type LibraryTrack struct {
+	Path        string  `json:"path"`
+	Root        string  `json:"root"`
+	Title       string  `json:"title"`
+	Artist      string  `json:"artist"`
+	Album       string  `json:"album"`
+	AlbumArtist string  `json:"album_artist"`
+	TrackNumber int     `json:"track_number"`
+	DiscNumber  int     `json:"disc_number"`
+	Year        string  `json:"year"`
+	ISRC        string  `json:"isrc,omitempty"`
+	SpotifyID   string  `json:"spotify_id,omitempty"`
+	TidalID     string  `json:"tidal_id,omitempty"`
+	QobuzID     string  `json:"qobuz_id,omitempty"`
+	Duration    float64 `json:"duration"`
+	Format      string  `json:"format"`
+	Size        int64   `json:"size"`
+	ModTime     int64   `json:"mod_time"`
+	IndexedAt   int64   `json:"indexed_at"`
+}
+
+type LibraryRoot struct {
+	Path       string `json:"path"`
+	TrackCount int    `json:"track_count"`
+	LastScan   int64  `json:"last_scan"`
+}
+
+type LibraryScanResult struct {
+	Root      string   `json:"root"`
+	Scanned   int      `json:"scanned"`
+	Indexed   int      `json:"indexed"`
+	Unchanged int      `json:"unchanged"`
+	Removed   int      `json:"removed"`
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors,omitempty"`
+	Duration  float64  `json:"duration"`
+}
+
+type LibraryQuery struct {
+	SpotifyID string `json:"spotify_id,omitempty"`
+	ISRC      string `json:"isrc,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	Duration  int    `json:"duration,omitempty"`
+}
+
+type LibraryMatch struct {
+	Track     LibraryTrack `json:"track"`
+	MatchedBy string       `json:"matched_by"`
+}
+
+func libraryPathKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return filepath.Clean(path)
+}
+
+func libraryUnderRoot(path, root string) bool {
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+func libraryNameKey(artist, title string) string {
+	primary := strings.ToLower(artist)
+	for _, sep := range []string{";", ",", " & ", " / ", " x ", " feat", " ft."} {
+		if idx := strings.Index(primary, sep); idx > 0 {
+			primary = primary[:idx]
+		}
+	}
+
+	artistTokens := normalizeForMatch(primary)
+	titleTokens := normalizeForMatch(libraryFeatRegex.ReplaceAllString(title, ""))
+	if len(artistTokens) == 0 || len(titleTokens) == 0 {
+		return ""
+	}
+
+	return strings.Join(artistTokens, " ") + "\x1f" + strings.Join(titleTokens, " ")
+}
+
+func libraryIndexKeys(track LibraryTrack) []string {
+	var keys []string
+	if track.SpotifyID != "" {
+		keys = append(keys, "spotify:"+track.SpotifyID)
+	}
+	if track.ISRC != "" {
+		keys = append(keys, "isrc:"+strings.ToUpper(track.ISRC))
+	}
+	if name := libraryNameKey(track.Artist, track.Title); name != "" {
+		keys = append(keys, "name:"+name)
+	}
+	return keys
+}
+
+func libraryBuckets(tx *bolt.Tx) (*bolt.Bucket, *bolt.Bucket, error) {
+	tracks, err := tx.CreateBucketIfNotExists([]byte(libraryTracksBucket))
+	if err != nil {
+		return nil, nil, err
+	}
+	index, err := tx.CreateBucketIfNotExists([]byte(libraryIndexBucket))
+	if err != nil {
+		return nil, nil, err
+	}
+	return tracks, index, nil
+}
+
+func deleteLibraryTrack(tracks, index *bolt.Bucket, path string) error {
+	existing := tracks.Get([]byte(path))
+	if existing == nil {
+		return nil
+	}
+
+	var prev LibraryTrack
+	if err := json.Unmarshal(existing, &prev); err == nil {
+		for _, key := range libraryIndexKeys(prev) {
+			if err := index.Delete([]byte(key + "\x00" + path)); err != nil {
+				return err
+			}
+		}
+	}
+	return tracks.Delete([]byte(path))
+}
+
+func putLibraryTrack(tracks, index *bolt.Bucket, track LibraryTrack) error {
+	if err := deleteLibraryTrack(tracks, index, track.Path); err != nil {
+		return err
+	}
+
+	buf, err := json.Marshal(track)
+	if err != nil {
+		return err
+	}
+	if err := tracks.Put([]byte(track.Path), buf); err != nil {
+		return err
+	}
+
+	for _, key := range libraryIndexKeys(track) {
+		if err := index.Put([]byte(key+"\x00"+track.Path), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readLibraryTrack(path, root string) (LibraryTrack, error) {
+	track := LibraryTrack{Path: path, Root: root}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return track, err
+	}
+	track.Size = info.Size()
+	track.ModTime = info.ModTime().UnixNano()
+	track.Format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+
+	meta, err := ReadAudioMetadata(path)
+	if err != nil {
+		return track, err
+	}
+	track.Title = meta.Title
+	track.Artist = meta.Artist
+	track.Album = meta.Album
+	track.AlbumArtist = meta.AlbumArtist
+	track.TrackNumber = meta.TrackNumber
+	track.DiscNumber = meta.DiscNumber
+	track.Year = meta.Year
+	track.ISRC = meta.ISRC
+	track.SpotifyID = meta.SpotifyID
+	track.TidalID = meta.TidalID
+	track.QobuzID = meta.QobuzID
+
+	if duration, err := GetAudioDuration(path); err == nil {
+		track.Duration = duration
+	}
+
+	track.IndexedAt = time.Now().Unix()
+	return track, nil
+}
+
+func ScanLibraryRoot(root string, full bool) (*LibraryScanResult, error) {
+	root = libraryPathKey(root)
+	return scanLibraryPath(root, root, full)
+}
+
+func scanLibraryPath(root, target string, full bool) (*LibraryScanResult, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("library database is not initialized")
+	}
+
+	result := &LibraryScanResult{Root: root}
+	start := time.Now()
+
+	files, err := ListAudioFiles(target)
+	if err != nil {
+		return result, err
+	}
+	result.Scanned = len(files)
+
+	known := make(map[string]LibraryTrack)
+	if !full {
+		historyDB.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(libraryTracksBucket))
+			if b == nil {
+				return nil
+			}
+			c := b.Cursor()
+			for k, v := c.Seek([]byte(target)); k != nil && strings.HasPrefix(string(k), target); k, v = c.Next() {
+				var track LibraryTrack
+				if json.Unmarshal(v, &track) == nil {
+					known[string(k)] = track
+				}
+			}
+			return nil
+		})
+	}
+
+	workers := runtime.NumCPU()
+	if workers > 8 {
+		workers = 8
+	}
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var indexed []LibraryTrack
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				track, err := readLibraryTrack(path, root)
+
+				mu.Lock()
+				if err != nil {
+					result.Failed++
+					if len(result.Errors) < libraryMaxScanErrors {
+						result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", path, err))
+					}
+				} else {
+					indexed = append(indexed, track)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, file := range files {
+		path := libraryPathKey(file.Path)
+		seen[path] = true
+
+		if prev, ok := known[path]; ok && prev.Root == root {
+			if info, err := os.Stat(path); err == nil && info.Size() == prev.Size && info.ModTime().UnixNano() == prev.ModTime {
+				result.Unchanged++
+				continue
+			}
+		}
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	err = historyDB.Update(func(tx *bolt.Tx) error {
+		tracks, index, err := libraryBuckets(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, track := range indexed {
+			if err := putLibraryTrack(tracks, index, track); err != nil {
+				return err
+			}
+		}
+		result.Indexed = len(indexed)
+
+		var stale []string
+		count := 0
+		c := tracks.Cursor()
+		for k, _ := c.Seek([]byte(root)); k != nil && strings.HasPrefix(string(k), root); k, _ = c.Next() {
+			path := string(k)
+			if !libraryUnderRoot(path, root) {
+				continue
+			}
+			if libraryUnderRoot(path, target) && !seen[path] {
+				stale = append(stale, path)
+			} else {
+				count++
+			}
+		}
+		for _, path := range stale {
+			if err := deleteLibraryTrack(tracks, index, path); err != nil {
+				return err
+			}
+		}
+		result.Removed = len(stale)
+
+		return putLibraryRoot(tx, LibraryRoot{Path: root, TrackCount: count, LastScan: time.Now().Unix()})
+	})
+
+	result.Duration = time.Since(start).Seconds()
+	if result.Indexed > 0 || result.Removed > 0 {
+		fmt.Printf("[Library] Scanned %s: %d files, %d indexed, %d unchanged, %d removed, %d failed (%.1fs)\n", target, result.Scanned, result.Indexed, result.Unchanged, result.Removed, result.Failed, result.Duration)
+	}
+	return result, err
+}
+
+func ScanLibrary(full bool) ([]LibraryScanResult, error) {
+	roots, err := GetLibraryRoots()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]LibraryScanResult, 0, len(roots))
+	for _, root := range roots {
+		result, err := ScanLibraryRoot(root.Path, full)
+		if result == nil {
+			result = &LibraryScanResult{Root: root.Path}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+		results = append(results, *result)
+	}
+	return results, nil
+}
+
+func IndexLibraryFile(path string) error {
+	if historyDB == nil {
+		return nil
+	}
+
+	path = libraryPathKey(path)
+	roots, err := GetLibraryRoots()
+	if err != nil {
+		return err
+	}
+
+	root := filepath.Dir(path)
+	for _, r := range roots {
+		if libraryUnderRoot(path, r.Path) {
+			root = r.Path
+			break
+		}
+	}
+
+	track, err := readLibraryTrack(path, root)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		tracks, index, err := libraryBuckets(tx)
+		if err != nil {
+			return err
+		}
+		return putLibraryTrack(tracks, index, track)
+	})
+}
+
+func MoveLibraryFile(oldPath, newPath string) error {
+	if historyDB == nil {
+		return nil
+	}
+
+	oldPath = libraryPathKey(oldPath)
+	newPath = libraryPathKey(newPath)
+
+	info, err := os.Stat(newPath)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		tracks, index, err := libraryBuckets(tx)
+		if err != nil {
+			return err
+		}
+
+		existing := tracks.Get([]byte(oldPath))
+		if existing == nil {
+			return nil
+		}
+
+		var track LibraryTrack
+		if err := json.Unmarshal(existing, &track); err != nil {
+			return err
+		}
+		if err := deleteLibraryTrack(tracks, index, oldPath); err != nil {
+			return err
+		}
+
+		track.Path = newPath
+		track.Size = info.Size()
+		track.ModTime = info.ModTime().UnixNano()
+		return putLibraryTrack(tracks, index, track)
+	})
+}
+
+func putLibraryRoot(tx *bolt.Tx, root LibraryRoot) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(libraryRootsBucket))
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(root)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(root.Path), buf)
+}
+
+func AddLibraryRoot(root string) error {
+	if historyDB == nil {
+		return fmt.Errorf("library database is not initialized")
+	}
+
+	root = libraryPathKey(root)
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("folder does not exist: %s", root)
+	}
+
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(libraryRootsBucket)); b != nil && b.Get([]byte(root)) != nil {
+			return nil
+		}
+		return putLibraryRoot(tx, LibraryRoot{Path: root})
+	})
+	if err == nil {
+		refreshLibraryWatcher()
+	}
+	return err
+}
+
+func RemoveLibraryRoot(root string) error {
+	if historyDB == nil {
+		return fmt.Errorf("library database is not initialized")
+	}
+
+	root = libraryPathKey(root)
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(libraryRootsBucket)); b != nil {
+			if err := b.Delete([]byte(root)); err != nil {
+				return err
+			}
+		}
+
+		tracks, index, err := libraryBuckets(tx)
+		if err != nil {
+			return err
+		}
+
+		var stale []string
+		c := tracks.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			var track LibraryTrack
+			if json.Unmarshal(v, &track) == nil && track.Root == root {
+				stale = append(stale, string(k))
+			}
+		}
+		for _, path := range stale {
+			if err := deleteLibraryTrack(tracks, index, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		refreshLibraryWatcher()
+	}
+	return err
+}
+
+func GetLibraryRoots() ([]LibraryRoot, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("library database is not initialized")
+	}
+
+	roots := []LibraryRoot{}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(libraryRootsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var root LibraryRoot
+			if err := json.Unmarshal(v, &root); err == nil {
+				roots = append(roots, root)
+			}
+			return nil
+		})
+	})
+	return roots, err
+}
+
+func lookupLibraryKey(tx *bolt.Tx, key string) []LibraryTrack {
+	tracks := tx.Bucket([]byte(libraryTracksBucket))
+	index := tx.Bucket([]byte(libraryIndexBucket))
+	if tracks == nil || index == nil {
+		return nil
+	}
+
+	var found []LibraryTrack
+	prefix := []byte(key + "\x00")
+	c := index.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && strings.HasPrefix(string(k), string(prefix)); k, _ = c.Next() {
+		var track LibraryTrack
+		if v := tracks.Get(k[len(prefix):]); v != nil && json.Unmarshal(v, &track) == nil {
+			found = append(found, track)
+		}
+	}
+	return found
+}
+
+func FindLibraryTrack(query LibraryQuery) (*LibraryMatch, error) {
+	if historyDB == nil {
+		return nil, nil
+	}
+
+	type lookup struct {
+		by  string
+		key string
+	}
+
+	var lookups []lookup
+	if query.SpotifyID != "" {
+		lookups = append(lookups, lookup{"spotify_id", "spotify:" + query.SpotifyID})
+	}
+	if isrc := strings.ToUpper(strings.TrimSpace(query.ISRC)); isValidISRC(isrc) {
+		lookups = append(lookups, lookup{"isrc", "isrc:" + isrc})
+	}
+	if name := libraryNameKey(query.Artist, query.Title); name != "" {
+		lookups = append(lookups, lookup{"name", "name:" + name})
+	}
+
+	var match *LibraryMatch
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		for _, l := range lookups {
+			for _, track := range lookupLibraryKey(tx, l.key) {
+				if l.by == "name" && query.Duration > 0 && track.Duration > 0 &&
+					math.Abs(track.Duration-float64(query.Duration)) > libraryDurationTolerance {
+					continue
+				}
+				if _, err := os.Stat(track.Path); err != nil {
+					continue
+				}
+				match = &LibraryMatch{Track: track, MatchedBy: l.by}
+				return nil
+			}
+		}
+		return nil
+	})
+
+	return match, err
+}
+
+func SearchLibrary(text string, limit int) ([]LibraryTrack, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("library database is not initialized")
+	}
+
+	terms := normalizeForMatch(text)
+	results := []LibraryTrack{}
+
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(libraryTracksBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var track LibraryTrack
+			if err := json.Unmarshal(v, &track); err != nil {
+				return nil
+			}
+
+			haystack := " " + strings.Join(normalizeForMatch(strings.Join([]string{track.Title, track.Artist, track.Album, track.AlbumArtist, track.ISRC, track.SpotifyID}, " ")), " ") + " "
+			for _, term := range terms {
+				if !strings.Contains(haystack, term) {
+					return nil
+				}
+			}
+			results = append(results, track)
+			return nil
+		})
+	})
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Artist != results[j].Artist {
+			return strings.ToLower(results[i].Artist) < strings.ToLower(results[j].Artist)
+		}
+		if results[i].Album != results[j].Album {
+			return strings.ToLower(results[i].Album) < strings.ToLower(results[j].Album)
+		}
+		if results[i].DiscNumber != results[j].DiscNumber {
+			return results[i].DiscNumber < results[j].DiscNumber
+		}
+		return results[i].TrackNumber < results[j].TrackNumber
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, err
+}
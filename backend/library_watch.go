@@ -0,0 +1,243 @@
+package backend
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	LibraryWatchModeNative  = "native"
+	LibraryWatchModePolling = "polling"
+
+	libraryWatchDebounce = 2 * time.Second
+	libraryPollInterval  = 60 * time.Second
+)
+
+type LibraryWatcherStatus struct {
+	Running    bool     `json:"running"`
+	Mode       string   `json:"mode,omitempty"`
+	Roots      []string `json:"roots,omitempty"`
+	LastUpdate int64    `json:"last_update,omitempty"`
+	LastError  string   `json:"last_error,omitempty"`
+}
+
+type dirWatcher interface {
+	Events() <-chan string
+	Close() error
+}
+
+type libraryWatcher struct {
+	roots []string
+	mode  string
+	dirs  dirWatcher
+	stop  chan struct{}
+	done  chan struct{}
+
+	mu         sync.Mutex
+	lastUpdate int64
+	lastError  string
+}
+
+var (
+	libraryWatcherLock    sync.Mutex
+	libraryWatcherEnabled bool
+	activeLibraryWatcher  *libraryWatcher
+)
+
+func StartLibraryWatcher() error {
+	roots, err := GetLibraryRoots()
+	if err != nil {
+		return err
+	}
+
+	libraryWatcherLock.Lock()
+	defer libraryWatcherLock.Unlock()
+
+	libraryWatcherEnabled = true
+	if activeLibraryWatcher != nil {
+		activeLibraryWatcher.close()
+		activeLibraryWatcher = nil
+	}
+
+	if len(roots) == 0 {
+		return nil
+	}
+
+	w := &libraryWatcher{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for _, root := range roots {
+		w.roots = append(w.roots, root.Path)
+	}
+
+	w.mode = LibraryWatchModeNative
+	w.dirs, err = newDirWatcher(w.roots)
+	if err != nil {
+		fmt.Printf("[Library] Native file watching unavailable, polling every %s: %v\n", libraryPollInterval, err)
+		w.mode = LibraryWatchModePolling
+		w.dirs = nil
+	}
+
+	fmt.Printf("[Library] Watching %d folder(s) (%s)\n", len(w.roots), w.mode)
+	go w.run()
+	activeLibraryWatcher = w
+	return nil
+}
+
+func StopLibraryWatcher() {
+	libraryWatcherLock.Lock()
+	defer libraryWatcherLock.Unlock()
+
+	libraryWatcherEnabled = false
+	if activeLibraryWatcher != nil {
+		activeLibraryWatcher.close()
+		activeLibraryWatcher = nil
+	}
+}
+
+func GetLibraryWatcherStatus() LibraryWatcherStatus {
+	libraryWatcherLock.Lock()
+	w := activeLibraryWatcher
+	libraryWatcherLock.Unlock()
+
+	if w == nil {
+		return LibraryWatcherStatus{}
+	}
+
+	running := true
+	select {
+	case <-w.done:
+		running = false
+	default:
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return LibraryWatcherStatus{
+		Running:    running,
+		Mode:       w.mode,
+		Roots:      w.roots,
+		LastUpdate: w.lastUpdate,
+		LastError:  w.lastError,
+	}
+}
+
+func refreshLibraryWatcher() {
+	libraryWatcherLock.Lock()
+	enabled := libraryWatcherEnabled
+	libraryWatcherLock.Unlock()
+
+	if enabled {
+		if err := StartLibraryWatcher(); err != nil {
+			fmt.Printf("[Library] Failed to restart watcher: %v\n", err)
+		}
+	}
+}
+
+func (w *libraryWatcher) close() {
+	close(w.stop)
+	if w.dirs != nil {
+		w.dirs.Close()
+	}
+	<-w.done
+}
+
+func (w *libraryWatcher) run() {
+	defer close(w.done)
+
+	if w.dirs != nil {
+		if w.watch() {
+			return
+		}
+
+		fmt.Printf("[Library] Native file watching stopped, polling every %s\n", libraryPollInterval)
+		w.mu.Lock()
+		w.mode = LibraryWatchModePolling
+		w.lastError = "native file watching stopped unexpectedly"
+		w.mu.Unlock()
+	}
+
+	ticker := time.NewTicker(libraryPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			for _, root := range w.roots {
+				w.rescan(root, root)
+			}
+		}
+	}
+}
+
+func (w *libraryWatcher) watch() bool {
+	dirty := make(map[string]bool)
+	timer := time.NewTimer(libraryWatchDebounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	events := w.dirs.Events()
+	for {
+		select {
+		case <-w.stop:
+			return true
+		case path, ok := <-events:
+			if !ok {
+				return false
+			}
+			dirty[libraryPathKey(path)] = true
+			timer.Reset(libraryWatchDebounce)
+		case <-timer.C:
+			for _, path := range collapseLibraryPaths(dirty) {
+				for _, root := range w.roots {
+					if libraryUnderRoot(path, root) {
+						w.rescan(root, path)
+						break
+					}
+				}
+			}
+			dirty = make(map[string]bool)
+		}
+	}
+}
+
+func (w *libraryWatcher) rescan(root, target string) {
+	result, err := scanLibraryPath(root, target, false)
+
+	w.mu.Lock()
+	if err != nil {
+		w.lastError = err.Error()
+	} else {
+		w.lastError = ""
+	}
+	if result != nil && (result.Indexed > 0 || result.Removed > 0) {
+		w.lastUpdate = time.Now().Unix()
+	}
+	w.mu.Unlock()
+
+	if result != nil && (result.Indexed > 0 || result.Removed > 0) {
+		emitDownloadEvent(EventLibraryUpdated, result)
+	}
+}
+
+func collapseLibraryPaths(paths map[string]bool) []string {
+	sorted := make([]string, 0, len(paths))
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	var collapsed []string
+	for _, path := range sorted {
+		if n := len(collapsed); n > 0 && libraryUnderRoot(path, collapsed[n-1]) {
+			continue
+		}
+		collapsed = append(collapsed, path)
+	}
+	return collapsed
+}
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// fsnotify uses inotify on Linux, ReadDirectoryChangesW on Windows and kqueue
+// on macOS. kqueue holds a descriptor per watched file, so very large libraries
+// can fail to register and fall back to polling.
+type fsnotifyWatcher struct {
+	watcher *fsnotify.Watcher
+	roots   []string
+	events  chan string
+	done    chan struct{}
+}
+
+func newDirWatcher(roots []string) (dirWatcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("file watcher init failed: %w", err)
+	}
+
+	w := &fsnotifyWatcher{
+		watcher: fw,
+		roots:   roots,
+		events:  make(chan string, 64),
+		done:    make(chan struct{}),
+	}
+
+	for _, root := range roots {
+		if err := w.addTree(root); err != nil {
+			fw.Close()
+			return nil, err
+		}
+	}
+
+	go w.readLoop()
+	return w, nil
+}
+
+func (w *fsnotifyWatcher) Events() <-chan string {
+	return w.events
+}
+
+func (w *fsnotifyWatcher) Close() error {
+	select {
+	case <-w.done:
+		return nil
+	default:
+		close(w.done)
+	}
+	return w.watcher.Close()
+}
+
+func (w *fsnotifyWatcher) addTree(root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (w *fsnotifyWatcher) emit(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+func (w *fsnotifyWatcher) readLoop() {
+	defer close(w.events)
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := w.addTree(event.Name); err != nil {
+						fmt.Printf("[Library] %v\n", err)
+					}
+				}
+			}
+
+			if !w.emit(event.Name) {
+				return
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			if !errors.Is(err, fsnotify.ErrEventOverflow) {
+				fmt.Printf("[Library] Watcher error: %v\n", err)
+				continue
+			}
+			for _, root := range w.roots {
+				if !w.emit(root) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	replayGainReferenceLUFS = -18.0
+	loudnessAbsoluteGate    = -70.0
+	loudnessRelativeGate    = -10.0
+	loudnessBlockSegments   = 4
+	truePeakTapsPerPhase    = 12
+)
+
+type LoudnessResult struct {
+	FilePath       string  `json:"file_path"`
+	IntegratedLUFS float64 `json:"integrated_lufs"`
+	TruePeak       float64 `json:"true_peak"`
+	TruePeakDBTP   float64 `json:"true_peak_dbtp"`
+	TrackGain      float64 `json:"track_gain"`
+
+	blockPowers []float64
+}
+
+type ReplayGainResult struct {
+	Tracks    []*LoudnessResult `json:"tracks"`
+	AlbumMode bool              `json:"album_mode"`
+	AlbumLUFS float64           `json:"album_lufs,omitempty"`
+	AlbumGain float64           `json:"album_gain,omitempty"`
+	AlbumPeak float64           `json:"album_peak,omitempty"`
+	Errors    []string          `json:"errors,omitempty"`
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+func kWeightingFilters(sampleRate float64) (biquad, biquad) {
+	f0 := 1681.974450955533
+	gain := 3.999843853973347
+	q := 0.7071752369554196
+
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+
+	highpass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highpass
+}
+
+func channelWeight(channel, channels int) float64 {
+	switch {
+	case channels == 6 && channel == 3:
+		return 0
+	case channels == 6 && channel >= 4:
+		return 1.41
+	case channels == 5 && channel >= 3:
+		return 1.41
+	}
+	return 1
+}
+
+type truePeakMeter struct {
+	phases  [][]float64
+	history []float64
+	pos     int
+	peak    float64
+}
+
+func newTruePeakMeter(sampleRate int) *truePeakMeter {
+	factor := 4
+	if sampleRate >= 192000 {
+		factor = 1
+	} else if sampleRate >= 96000 {
+		factor = 2
+	}
+
+	taps := truePeakTapsPerPhase
+	half := float64(taps) / 2
+	phases := make([][]float64, factor)
+	for p := 0; p < factor; p++ {
+		coeffs := make([]float64, taps)
+		for k := 0; k < taps; k++ {
+			t := float64(k) - half + float64(p)/float64(factor)
+			if math.Abs(t) >= half {
+				continue
+			}
+			sinc := 1.0
+			if t != 0 {
+				sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+			}
+			coeffs[k] = sinc * (0.5 + 0.5*math.Cos(math.Pi*t/half))
+		}
+		phases[p] = coeffs
+	}
+
+	return &truePeakMeter{
+		phases:  phases,
+		history: make([]float64, 2*taps),
+	}
+}
+
+func (m *truePeakMeter) process(x float64) {
+	taps := truePeakTapsPerPhase
+	m.pos = (m.pos + 1) % taps
+	m.history[m.pos] = x
+	m.history[m.pos+taps] = x
+
+	if len(m.phases) == 1 {
+		if v := math.Abs(x); v > m.peak {
+			m.peak = v
+		}
+		return
+	}
+
+	window := m.history[m.pos+1 : m.pos+1+taps]
+	for _, coeffs := range m.phases {
+		var y float64
+		for k, c := range coeffs {
+			y += window[taps-1-k] * c
+		}
+		if v := math.Abs(y); v > m.peak {
+			m.peak = v
+		}
+	}
+}
+
+func MeasureLoudness(filepath string) (*LoudnessResult, error) {
+	dec, err := OpenAudioDecoder(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	info := dec.Info()
+	sampleRate := info.SampleRate
+	channels := info.Channels
+	if sampleRate == 0 || channels == 0 {
+		return nil, fmt.Errorf("invalid stream info")
+	}
+
+	shelves := make([]biquad, channels)
+	highpasses := make([]biquad, channels)
+	meters := make([]*truePeakMeter, channels)
+	weights := make([]float64, channels)
+	for ch := 0; ch < channels; ch++ {
+		shelves[ch], highpasses[ch] = kWeightingFilters(float64(sampleRate))
+		meters[ch] = newTruePeakMeter(sampleRate)
+		weights[ch] = channelWeight(ch, channels)
+	}
+
+	segmentLen := sampleRate / 10
+	var segments []float64
+	var segmentSum float64
+	segmentFill := 0
+
+	for {
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode frame: %w", err)
+		}
+
+		n := len(frame[0])
+		for i := 0; i < n; i++ {
+			for ch := 0; ch < channels; ch++ {
+				x := frame[ch][i]
+				meters[ch].process(x)
+
+				y := highpasses[ch].process(shelves[ch].process(x))
+				segmentSum += weights[ch] * y * y
+			}
+
+			segmentFill++
+			if segmentFill == segmentLen {
+				segments = append(segments, segmentSum)
+				segmentSum = 0
+				segmentFill = 0
+			}
+		}
+	}
+
+	result := &LoudnessResult{FilePath: filepath}
+
+	blockLen := float64(segmentLen * loudnessBlockSegments)
+	for i := 0; i+loudnessBlockSegments <= len(segments); i++ {
+		var sum float64
+		for _, s := range segments[i : i+loudnessBlockSegments] {
+			sum += s
+		}
+		result.blockPowers = append(result.blockPowers, sum/blockLen)
+	}
+
+	for _, m := range meters {
+		if m.peak > result.TruePeak {
+			result.TruePeak = m.peak
+		}
+	}
+
+	result.IntegratedLUFS = gatedLoudness(result.blockPowers)
+	result.TrackGain = replayGainFor(result.IntegratedLUFS)
+	result.TruePeakDBTP = amplitudeToDB(result.TruePeak)
+
+	return result, nil
+}
+
+func gatedLoudness(blockPowers []float64) float64 {
+	var gated []float64
+	var sum float64
+	for _, p := range blockPowers {
+		if powerToLUFS(p) > loudnessAbsoluteGate {
+			gated = append(gated, p)
+			sum += p
+		}
+	}
+	if len(gated) == 0 {
+		return loudnessAbsoluteGate
+	}
+
+	threshold := powerToLUFS(sum/float64(len(gated))) + loudnessRelativeGate
+
+	var total float64
+	count := 0
+	for _, p := range gated {
+		if powerToLUFS(p) > threshold {
+			total += p
+			count++
+		}
+	}
+	if count == 0 {
+		return loudnessAbsoluteGate
+	}
+
+	return powerToLUFS(total / float64(count))
+}
+
+func powerToLUFS(power float64) float64 {
+	if power <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(power)
+}
+
+func amplitudeToDB(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return loudnessAbsoluteGate
+	}
+	return 20 * math.Log10(amplitude)
+}
+
+func replayGainFor(lufs float64) float64 {
+	return math.Round((replayGainReferenceLUFS-lufs)*100) / 100
+}
+
+func ScanReplayGain(filePaths []string, albumMode bool) *ReplayGainResult {
+	result := &ReplayGainResult{AlbumMode: albumMode}
+
+	var albumBlocks []float64
+	for _, filePath := range filePaths {
+		fmt.Printf("Scanning loudness: %s\n", filePath)
+		track, err := MeasureLoudness(filePath)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", filePath, err))
+			continue
+		}
+
+		fmt.Printf("  %.2f LUFS, true peak %.2f dBTP, gain %+.2f dB\n", track.IntegratedLUFS, track.TruePeakDBTP, track.TrackGain)
+		result.Tracks = append(result.Tracks, track)
+		albumBlocks = append(albumBlocks, track.blockPowers...)
+		if track.TruePeak > result.AlbumPeak {
+			result.AlbumPeak = track.TruePeak
+		}
+	}
+
+	if albumMode && len(result.Tracks) > 0 {
+		result.AlbumLUFS = gatedLoudness(albumBlocks)
+		result.AlbumGain = replayGainFor(result.AlbumLUFS)
+	} else {
+		result.AlbumPeak = 0
+	}
+
+	return result
+}
+
+func ApplyReplayGain(filePaths []string, albumMode bool) (*ReplayGainResult, error) {
+	result := ScanReplayGain(filePaths, albumMode)
+	if len(result.Tracks) == 0 {
+		return result, fmt.Errorf("no tracks could be scanned")
+	}
+
+	for _, track := range result.Tracks {
+		tags := &ReplayGainTags{
+			TrackGain: track.TrackGain,
+			TrackPeak: track.TruePeak,
+		}
+		if albumMode {
+			tags.HasAlbum = true
+			tags.AlbumGain = result.AlbumGain
+			tags.AlbumPeak = result.AlbumPeak
+		}
+
+		if err := EmbedReplayGain(track.FilePath, tags); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", track.FilePath, err))
+		}
+	}
+
+	return result, nil
+}
@@ -27,9 +27,10 @@ type LRCLibResponse struct {
 }
 
 type LyricsLine struct {
-	StartTimeMs string `json:"startTimeMs"`
-	Words       string `json:"words"`
-	EndTimeMs   string `json:"endTimeMs"`
+	StartTimeMs string           `json:"startTimeMs"`
+	Words       string           `json:"words"`
+	EndTimeMs   string           `json:"endTimeMs"`
+	Syllables   []LyricsSyllable `json:"syllables,omitempty"`
 }
 
 type LyricsResponse struct {
@@ -51,14 +52,17 @@ type LyricsDownloadRequest struct {
 	Position            int    `json:"position"`
 	UseAlbumTrackNumber bool   `json:"use_album_track_number"`
 	DiscNumber          int    `json:"disc_number"`
+	Format              string `json:"format,omitempty"`
 }
 
 type LyricsDownloadResponse struct {
-	Success       bool   `json:"success"`
-	Message       string `json:"message"`
-	File          string `json:"file,omitempty"`
-	Error         string `json:"error,omitempty"`
-	AlreadyExists bool   `json:"already_exists,omitempty"`
+	Success       bool              `json:"success"`
+	Message       string            `json:"message"`
+	File          string            `json:"file,omitempty"`
+	Error         string            `json:"error,omitempty"`
+	AlreadyExists bool              `json:"already_exists,omitempty"`
+	Source        string            `json:"source,omitempty"`
+	Candidates    []LyricsCandidate `json:"candidates,omitempty"`
 }
 
 type LyricsClient struct {
@@ -72,6 +76,15 @@ func NewLyricsClient() *LyricsClient {
 }
 
 func (c *LyricsClient) FetchLyricsWithMetadata(trackName, artistName string, duration int) (*LyricsResponse, error) {
+	lrcLibResp, err := c.fetchLRCLibExact(trackName, artistName, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convertLRCLibToLyricsResponse(lrcLibResp), nil
+}
+
+func (c *LyricsClient) fetchLRCLibExact(trackName, artistName string, duration int) (*LRCLibResponse, error) {
 
 	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9scmNsaWIubmV0L2FwaS9nZXQ/YXJ0aXN0X25hbWU9")
 	apiURL := fmt.Sprintf("%s%s&track_name=%s",
@@ -103,7 +116,7 @@ func (c *LyricsClient) FetchLyricsWithMetadata(trackName, artistName string, dur
 		return nil, fmt.Errorf("failed to parse LRCLIB response: %v", err)
 	}
 
-	return c.convertLRCLibToLyricsResponse(&lrcLibResp), nil
+	return &lrcLibResp, nil
 }
 
 func (c *LyricsClient) convertLRCLibToLyricsResponse(lrcLib *LRCLibResponse) *LyricsResponse {
@@ -138,9 +151,14 @@ func (c *LyricsClient) convertLRCLibToLyricsResponse(lrcLib *LRCLibResponse) *Ly
 				words := strings.TrimSpace(line[closeBracket+1:])
 
 				ms := lrcTimestampToMs(timestamp)
+				words, syllables := parseEnhancedLRCWords(words)
+				if len(syllables) > 0 {
+					resp.SyncType = "SYLLABLE_SYNCED"
+				}
 				resp.Lines = append(resp.Lines, LyricsLine{
 					StartTimeMs: fmt.Sprintf("%d", ms),
 					Words:       words,
+					Syllables:   syllables,
 				})
 				continue
 			}
@@ -156,16 +174,35 @@ func (c *LyricsClient) convertLRCLibToLyricsResponse(lrcLib *LRCLibResponse) *Ly
 }
 
 func lrcTimestampToMs(timestamp string) int64 {
-	var minutes, seconds, centiseconds int64
+	ms, _ := parseLRCTimeMs(timestamp)
+	return ms
+}
 
-	n, _ := fmt.Sscanf(timestamp, "%d:%d.%d", &minutes, &seconds, &centiseconds)
-	if n >= 2 {
-		return minutes*60*1000 + seconds*1000 + centiseconds*10
+func (c *LyricsClient) FetchLyricsFromLRCLibSearch(trackName, artistName string) (*LyricsResponse, error) {
+	results, err := c.searchLRCLib(trackName, artistName)
+	if err != nil {
+		return nil, err
 	}
-	return 0
+
+	var best *LRCLibResponse
+	for i := range results {
+		if results[i].SyncedLyrics != "" {
+			best = &results[i]
+			break
+		}
+		if best == nil && results[i].PlainLyrics != "" {
+			best = &results[i]
+		}
+	}
+
+	if best == nil {
+		best = &results[0]
+	}
+
+	return c.convertLRCLibToLyricsResponse(best), nil
 }
 
-func (c *LyricsClient) FetchLyricsFromLRCLibSearch(trackName, artistName string) (*LyricsResponse, error) {
+func (c *LyricsClient) searchLRCLib(trackName, artistName string) ([]LRCLibResponse, error) {
 	query := fmt.Sprintf("%s %s", artistName, trackName)
 	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9scmNsaWIubmV0L2FwaS9zZWFyY2g/cT0=")
 	apiURL := fmt.Sprintf("%s%s", string(apiBase), url.QueryEscape(query))
@@ -194,22 +231,7 @@ func (c *LyricsClient) FetchLyricsFromLRCLibSearch(trackName, artistName string)
 		return nil, fmt.Errorf("no results found")
 	}
 
-	var best *LRCLibResponse
-	for i := range results {
-		if results[i].SyncedLyrics != "" {
-			best = &results[i]
-			break
-		}
-		if best == nil && results[i].PlainLyrics != "" {
-			best = &results[i]
-		}
-	}
-
-	if best == nil {
-		best = &results[0]
-	}
-
-	return c.convertLRCLibToLyricsResponse(best), nil
+	return results, nil
 }
 
 func simplifyTrackName(name string) string {
@@ -225,35 +247,17 @@ func simplifyTrackName(name string) string {
 }
 
 func (c *LyricsClient) FetchLyricsAllSources(spotifyID, trackName, artistName string, duration int) (*LyricsResponse, string, error) {
-
-	resp, err := c.FetchLyricsWithMetadata(trackName, artistName, duration)
-	if err == nil && resp != nil && !resp.Error && len(resp.Lines) > 0 {
-		return resp, "LRCLIB", nil
-	}
-	fmt.Printf("   LRCLIB exact: %v\n", err)
-
-	resp, err = c.FetchLyricsFromLRCLibSearch(trackName, artistName)
-	if err == nil && resp != nil && !resp.Error && len(resp.Lines) > 0 {
-		return resp, "LRCLIB Search", nil
-	}
-	fmt.Printf("   LRCLIB search: %v\n", err)
-
-	simplifiedTrack := simplifyTrackName(trackName)
-	if simplifiedTrack != trackName {
-		fmt.Printf("   Trying simplified name: %s\n", simplifiedTrack)
-
-		resp, err = c.FetchLyricsWithMetadata(simplifiedTrack, artistName, duration)
-		if err == nil && resp != nil && !resp.Error && len(resp.Lines) > 0 {
-			return resp, "LRCLIB (simplified)", nil
-		}
-
-		resp, err = c.FetchLyricsFromLRCLibSearch(simplifiedTrack, artistName)
-		if err == nil && resp != nil && !resp.Error && len(resp.Lines) > 0 {
-			return resp, "LRCLIB Search (simplified)", nil
-		}
+	result, err := c.SearchLyrics(LyricsQuery{
+		SpotifyID:  spotifyID,
+		TrackName:  trackName,
+		ArtistName: artistName,
+		Duration:   duration,
+	})
+	if err != nil {
+		return nil, "", err
 	}
 
-	return nil, "", fmt.Errorf("lyrics not found in any source")
+	return result.Best.Lyrics, result.Best.Source, nil
 }
 
 func (c *LyricsClient) ConvertToLRC(lyrics *LyricsResponse, trackName, artistName string) string {
@@ -434,6 +438,7 @@ func (c *LyricsClient) DownloadLyrics(req LyricsDownloadRequest) (*LyricsDownloa
 		filenameFormat = "title-artist"
 	}
 	filename := buildLyricsFilename(req.TrackName, req.ArtistName, req.AlbumName, req.AlbumArtist, req.ReleaseDate, filenameFormat, req.TrackNumber, req.Position, req.DiscNumber)
+	filename = strings.TrimSuffix(filename, ".lrc") + lyricsFileExtension(req.Format)
 	filePath := filepath.Join(outputDir, filename)
 
 	if fileInfo, err := os.Stat(filePath); err == nil && fileInfo.Size() > 0 {
@@ -455,15 +460,31 @@ func (c *LyricsClient) DownloadLyrics(req LyricsDownloadRequest) (*LyricsDownloa
 		}
 	}
 
-	lyrics, _, err := c.FetchLyricsAllSources(req.SpotifyID, req.TrackName, req.ArtistName, audioDuration)
+	searchResult, err := c.SearchLyrics(LyricsQuery{
+		SpotifyID:  req.SpotifyID,
+		TrackName:  req.TrackName,
+		ArtistName: req.ArtistName,
+		AlbumName:  req.AlbumName,
+		Duration:   audioDuration,
+		Dir:        outputDir,
+	})
 	if err != nil {
 		return &LyricsDownloadResponse{
-			Success: false,
-			Error:   err.Error(),
+			Success:    false,
+			Error:      err.Error(),
+			Candidates: searchResult.Candidates,
 		}, err
 	}
+	lyrics := searchResult.Best.Lyrics
 
-	lrcContent := c.ConvertToLRC(lyrics, req.TrackName, req.ArtistName)
+	lrcContent, err := c.FormatLyrics(lyrics, req.Format, req.TrackName, req.ArtistName)
+	if err != nil {
+		return &LyricsDownloadResponse{
+			Success:    false,
+			Error:      err.Error(),
+			Candidates: searchResult.Candidates,
+		}, err
+	}
 
 	if err := os.WriteFile(filePath, []byte(lrcContent), 0644); err != nil {
 		return &LyricsDownloadResponse{
@@ -473,8 +494,10 @@ func (c *LyricsClient) DownloadLyrics(req LyricsDownloadRequest) (*LyricsDownloa
 	}
 
 	return &LyricsDownloadResponse{
-		Success: true,
-		Message: "Lyrics downloaded successfully",
-		File:    filePath,
+		Success:    true,
+		Message:    "Lyrics downloaded successfully",
+		File:       filePath,
+		Source:     searchResult.Best.Source,
+		Candidates: searchResult.Candidates,
 	}, nil
 }
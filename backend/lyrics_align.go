@@ -0,0 +1,542 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	LyricsAlignModeRewrite = "rewrite"
+	LyricsAlignModeOffset  = "offset"
+
+	alignHopMs            = 10
+	alignDefaultMaxOffset = 20000
+	alignOffsetStepMs     = 10
+	alignDriftRange       = 0.05
+	alignDriftStep        = 0.001
+	alignMinDrift         = 0.002
+	alignMinShiftMs       = 100
+	alignMinGain          = 0.1
+	alignDriftMinGain     = 0.05
+	alignMinLines         = 3
+	alignSilenceFloorDB   = -60.0
+	alignSilenceRangeDB   = 50.0
+	alignOnsetWindowHops  = 20
+)
+
+var (
+	lrcLineTimeRegex   = regexp.MustCompile(`^\[(\d+:\d+(?:\.\d+)?)\]`)
+	lrcWordTimeRegex   = regexp.MustCompile(`<(\d+:\d+(?:\.\d+)?)>`)
+	lrcOffsetTagRegex  = regexp.MustCompile(`(?i)^\[offset:\s*([+-]?\d+)\s*\]$`)
+	lrcMetadataTagLine = regexp.MustCompile(`^\[[A-Za-z]+:.*\]$`)
+)
+
+type LyricsAlignOptions struct {
+	Mode        string `json:"mode"`
+	DryRun      bool   `json:"dry_run"`
+	MaxOffsetMs int    `json:"max_offset_ms,omitempty"`
+	AllowDrift  bool   `json:"allow_drift"`
+}
+
+type LyricsAlignment struct {
+	FilePath      string  `json:"file_path"`
+	LyricsSource  string  `json:"lyrics_source,omitempty"`
+	AudioDuration float64 `json:"audio_duration"`
+	ContentStart  float64 `json:"content_start"`
+	ContentEnd    float64 `json:"content_end"`
+	LyricsStart   float64 `json:"lyrics_start"`
+	LyricsEnd     float64 `json:"lyrics_end"`
+	OffsetMs      int64   `json:"offset_ms"`
+	Drift         float64 `json:"drift"`
+	Confidence    float64 `json:"confidence"`
+	Mode          string  `json:"mode,omitempty"`
+	Changed       bool    `json:"changed"`
+	Applied       bool    `json:"applied"`
+	Message       string  `json:"message,omitempty"`
+	Error         string  `json:"error,omitempty"`
+}
+
+type LyricsAlignBatchResult struct {
+	Results []*LyricsAlignment `json:"results"`
+	Aligned int                `json:"aligned"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+}
+
+type alignEnvelope struct {
+	onsets       []float64
+	contentStart int
+	contentEnd   int
+	duration     float64
+}
+
+type alignTransform struct {
+	anchorMs float64
+	offsetMs float64
+	scale    float64
+}
+
+func (t alignTransform) apply(ms float64) float64 {
+	return t.anchorMs + t.offsetMs + t.scale*(ms-t.anchorMs)
+}
+
+func parseLRCTimeMs(timestamp string) (int64, bool) {
+	colon := strings.Index(timestamp, ":")
+	if colon <= 0 {
+		return 0, false
+	}
+	minutes, err := strconv.ParseInt(timestamp[:colon], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	seconds, err := strconv.ParseFloat(timestamp[colon+1:], 64)
+	if err != nil {
+		return 0, false
+	}
+	return minutes*60000 + int64(math.Round(seconds*1000)), true
+}
+
+func parseLRCOffsetTag(lyrics string) int64 {
+	for _, line := range strings.Split(lyrics, "\n") {
+		if m := lrcOffsetTagRegex.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+			offset, _ := strconv.ParseInt(m[1], 10, 64)
+			return offset
+		}
+	}
+	return 0
+}
+
+func lyricsLineTimesMs(lyrics string) []float64 {
+	offset := parseLRCOffsetTag(lyrics)
+
+	var times []float64
+	for _, line := range strings.Split(lyrics, "\n") {
+		rest := strings.TrimSpace(line)
+		var lineTimes []float64
+		for {
+			m := lrcLineTimeRegex.FindStringSubmatchIndex(rest)
+			if m == nil {
+				break
+			}
+			if ms, ok := parseLRCTimeMs(rest[m[2]:m[3]]); ok {
+				lineTimes = append(lineTimes, float64(ms-offset))
+			}
+			rest = rest[m[1]:]
+		}
+
+		if strings.TrimSpace(lrcWordTimeRegex.ReplaceAllString(rest, "")) == "" {
+			continue
+		}
+		times = append(times, lineTimes...)
+	}
+
+	return times
+}
+
+func buildAlignEnvelope(filePath string) (*alignEnvelope, error) {
+	dec, err := OpenAudioDecoder(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Close()
+
+	info := dec.Info()
+	if info.SampleRate == 0 || info.Channels == 0 {
+		return nil, fmt.Errorf("invalid stream info")
+	}
+
+	hopLen := info.SampleRate * alignHopMs / 1000
+	filter := bandPassFilter(float64(info.SampleRate), 1000, 0.5)
+
+	var levels, voice []float64
+	var levelSum, voiceSum float64
+	fill := 0
+
+	for {
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode frame: %w", err)
+		}
+
+		n := len(frame[0])
+		for i := 0; i < n; i++ {
+			var x float64
+			for ch := range frame {
+				x += frame[ch][i]
+			}
+			x /= float64(len(frame))
+
+			y := filter.process(x)
+			levelSum += x * x
+			voiceSum += y * y
+
+			fill++
+			if fill == hopLen {
+				levels = append(levels, powerToDB(levelSum/float64(hopLen)))
+				voice = append(voice, powerToDB(voiceSum/float64(hopLen)))
+				levelSum, voiceSum = 0, 0
+				fill = 0
+			}
+		}
+	}
+
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("no audio samples decoded")
+	}
+
+	peak := math.Inf(-1)
+	for _, l := range levels {
+		peak = math.Max(peak, l)
+	}
+	threshold := math.Max(alignSilenceFloorDB, peak-alignSilenceRangeDB)
+
+	env := &alignEnvelope{
+		contentStart: -1,
+		duration:     float64(len(levels)*alignHopMs) / 1000,
+	}
+	for i, l := range levels {
+		if l > threshold {
+			if env.contentStart < 0 {
+				env.contentStart = i
+			}
+			env.contentEnd = i + 1
+		}
+	}
+	if env.contentStart < 0 {
+		return nil, fmt.Errorf("audio is silent")
+	}
+
+	flux := make([]float64, len(voice))
+	for i := 3; i < len(voice); i++ {
+		if levels[i] <= threshold {
+			continue
+		}
+		cur := (voice[i] + voice[i-1]) / 2
+		prev := (voice[i-2] + voice[i-3]) / 2
+		flux[i] = math.Max(0, cur-prev)
+	}
+
+	env.onsets = make([]float64, len(flux))
+	for i := range flux {
+		for d := -alignOnsetWindowHops; d <= alignOnsetWindowHops; d++ {
+			j := i + d
+			if j < 0 || j >= len(flux) {
+				continue
+			}
+			weight := 1 - math.Abs(float64(d))/float64(alignOnsetWindowHops+1)
+			env.onsets[i] = math.Max(env.onsets[i], flux[j]*weight)
+		}
+	}
+
+	return env, nil
+}
+
+func bandPassFilter(sampleRate, f0, q float64) biquad {
+	w0 := 2 * math.Pi * f0 / sampleRate
+	alpha := math.Sin(w0) / (2 * q)
+	a0 := 1 + alpha
+
+	return biquad{
+		b0: alpha / a0,
+		b1: 0,
+		b2: -alpha / a0,
+		a1: -2 * math.Cos(w0) / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
+
+func powerToDB(power float64) float64 {
+	return 10 * math.Log10(power+1e-12)
+}
+
+func (e *alignEnvelope) score(times []float64, t alignTransform, penalty float64) float64 {
+	var total float64
+	for _, ms := range times {
+		idx := int(math.Round(t.apply(ms) / alignHopMs))
+		if idx < e.contentStart || idx >= e.contentEnd {
+			total -= penalty
+			continue
+		}
+		total += e.onsets[idx]
+	}
+
+	total -= penalty * 0.01 * math.Abs(t.offsetMs) / 1000
+	return total / float64(len(times))
+}
+
+func (e *alignEnvelope) meanOnset() float64 {
+	var sum float64
+	for _, o := range e.onsets[e.contentStart:e.contentEnd] {
+		sum += o
+	}
+	return sum / float64(e.contentEnd-e.contentStart)
+}
+
+func estimateLyricsAlignment(env *alignEnvelope, times []float64, opts LyricsAlignOptions) (alignTransform, float64, float64) {
+	maxOffset := float64(opts.MaxOffsetMs)
+	if maxOffset <= 0 {
+		maxOffset = alignDefaultMaxOffset
+	}
+
+	penalty := env.meanOnset()
+	identity := alignTransform{anchorMs: times[0], scale: 1}
+	identityScore := env.score(times, identity, penalty)
+
+	best, bestScore := identity, identityScore
+	for offset := -maxOffset; offset <= maxOffset; offset += alignOffsetStepMs {
+		t := alignTransform{anchorMs: times[0], offsetMs: offset, scale: 1}
+		if s := env.score(times, t, penalty); s > bestScore {
+			best, bestScore = t, s
+		}
+	}
+
+	if opts.AllowDrift {
+		driftBest, driftScore := best, bestScore
+		for scale := 1 - alignDriftRange; scale <= 1+alignDriftRange+1e-9; scale += alignDriftStep {
+			if math.Abs(scale-1) < alignMinDrift {
+				continue
+			}
+			for offset := -maxOffset; offset <= maxOffset; offset += alignOffsetStepMs {
+				t := alignTransform{anchorMs: times[0], offsetMs: offset, scale: scale}
+				if s := env.score(times, t, penalty); s > driftScore {
+					driftBest, driftScore = t, s
+				}
+			}
+		}
+		if driftScore > bestScore+math.Abs(bestScore)*alignDriftMinGain {
+			best, bestScore = driftBest, driftScore
+		}
+	}
+
+	return best, bestScore, identityScore
+}
+
+func AlignLyrics(audioPath, lyrics string, opts LyricsAlignOptions) (*LyricsAlignment, string, error) {
+	result := &LyricsAlignment{FilePath: audioPath, Drift: 1}
+
+	times := lyricsLineTimesMs(lyrics)
+	if len(times) < alignMinLines {
+		return result, lyrics, fmt.Errorf("lyrics have too few synced lines to align")
+	}
+
+	env, err := buildAlignEnvelope(audioPath)
+	if err != nil {
+		return result, lyrics, err
+	}
+
+	result.AudioDuration = env.duration
+	result.ContentStart = float64(env.contentStart*alignHopMs) / 1000
+	result.ContentEnd = float64(env.contentEnd*alignHopMs) / 1000
+	result.LyricsStart = times[0] / 1000
+	result.LyricsEnd = times[len(times)-1] / 1000
+
+	transform, bestScore, identityScore := estimateLyricsAlignment(env, times, opts)
+	if bestScore > 0 {
+		result.Confidence = math.Max(0, math.Min(1, (bestScore-identityScore)/bestScore))
+	}
+
+	shift := transform.offsetMs
+	if math.Abs(transform.scale-1) > 1e-9 {
+		lastShift := transform.apply(times[len(times)-1]) - times[len(times)-1]
+		if math.Abs(lastShift) > math.Abs(shift) {
+			shift = lastShift
+		}
+	}
+
+	gain := bestScore - identityScore
+	if math.Abs(shift) < alignMinShiftMs || gain <= math.Abs(identityScore)*alignMinGain {
+		result.Message = "Lyrics already match the audio"
+		return result, lyrics, nil
+	}
+
+	result.OffsetMs = int64(math.Round(transform.offsetMs))
+	result.Drift = math.Round(transform.scale*10000) / 10000
+	result.Changed = true
+
+	mode := strings.ToLower(opts.Mode)
+	if mode == "" {
+		mode = LyricsAlignModeRewrite
+	}
+	if mode == LyricsAlignModeOffset && result.Drift != 1 {
+		mode = LyricsAlignModeRewrite
+		result.Message = "Drift cannot be expressed as an [offset:] tag, timestamps were rewritten"
+	}
+	result.Mode = mode
+
+	var aligned string
+	switch mode {
+	case LyricsAlignModeOffset:
+		aligned = setLRCOffsetTag(lyrics, parseLRCOffsetTag(lyrics)-result.OffsetMs)
+	case LyricsAlignModeRewrite:
+		aligned = rewriteLRCTimestamps(lyrics, transform)
+	default:
+		return result, lyrics, fmt.Errorf("unsupported alignment mode: %s", opts.Mode)
+	}
+
+	if result.Message == "" {
+		if result.Drift != 1 {
+			result.Message = fmt.Sprintf("Shifted lyrics by %+dms with %.2f%% drift", result.OffsetMs, (result.Drift-1)*100)
+		} else {
+			result.Message = fmt.Sprintf("Shifted lyrics by %+dms", result.OffsetMs)
+		}
+	}
+
+	return result, aligned, nil
+}
+
+func rewriteLRCTimestamps(lyrics string, t alignTransform) string {
+	offset := float64(parseLRCOffsetTag(lyrics))
+
+	shift := func(timestamp string) string {
+		ms, ok := parseLRCTimeMs(timestamp)
+		if !ok {
+			return timestamp
+		}
+		return formatLRCTime(int64(math.Max(0, math.Round(t.apply(float64(ms)-offset)))))
+	}
+
+	var out []string
+	for _, line := range strings.Split(lyrics, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if lrcOffsetTagRegex.MatchString(trimmed) {
+			continue
+		}
+
+		var sb strings.Builder
+		rest := trimmed
+		for {
+			m := lrcLineTimeRegex.FindStringSubmatchIndex(rest)
+			if m == nil {
+				break
+			}
+			sb.WriteString("[" + shift(rest[m[2]:m[3]]) + "]")
+			rest = rest[m[1]:]
+		}
+		if sb.Len() == 0 {
+			out = append(out, line)
+			continue
+		}
+
+		sb.WriteString(lrcWordTimeRegex.ReplaceAllStringFunc(rest, func(tag string) string {
+			return "<" + shift(tag[1:len(tag)-1]) + ">"
+		}))
+		out = append(out, sb.String())
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func setLRCOffsetTag(lyrics string, offsetMs int64) string {
+	tag := fmt.Sprintf("[offset:%+d]", offsetMs)
+
+	lines := strings.Split(lyrics, "\n")
+	insertAt := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if lrcOffsetTagRegex.MatchString(trimmed) {
+			lines[i] = tag
+			return strings.Join(lines, "\n")
+		}
+		if lrcMetadataTagLine.MatchString(trimmed) && !lrcLineTimeRegex.MatchString(trimmed) {
+			insertAt = i + 1
+		}
+	}
+
+	lines = append(lines[:insertAt], append([]string{tag}, lines[insertAt:]...)...)
+	return strings.Join(lines, "\n")
+}
+
+func findLyricsForAudio(audioPath string) (string, string, error) {
+	sidecar := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".lrc"
+	candidates := []string{sidecar}
+
+	if meta, err := ReadAudioMetadata(audioPath); err == nil && meta.Title != "" {
+		candidates = append(candidates, findLocalLyricsFiles(filepath.Dir(audioPath), meta.Title, meta.Artist)...)
+	}
+
+	for _, path := range candidates {
+		if data, err := os.ReadFile(path); err == nil && len(data) > 0 {
+			return string(data), path, nil
+		}
+	}
+
+	lyrics, err := ExtractLyrics(audioPath)
+	if err != nil {
+		return "", "", err
+	}
+	if lyrics == "" {
+		return "", "", nil
+	}
+	return lyrics, "embedded", nil
+}
+
+func AlignLyricsFile(audioPath string, opts LyricsAlignOptions) (*LyricsAlignment, error) {
+	lyrics, source, err := findLyricsForAudio(audioPath)
+	if err != nil {
+		return &LyricsAlignment{FilePath: audioPath, Drift: 1, Error: err.Error()}, err
+	}
+	if lyrics == "" {
+		return &LyricsAlignment{FilePath: audioPath, Drift: 1, Message: "No lyrics found"}, nil
+	}
+
+	fmt.Printf("[AlignLyrics] %s (lyrics from %s)\n", audioPath, source)
+
+	result, aligned, err := AlignLyrics(audioPath, lyrics, opts)
+	result.LyricsSource = source
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+
+	fmt.Printf("[AlignLyrics] content %.2fs-%.2fs, lyrics %.2fs-%.2fs: %s\n", result.ContentStart, result.ContentEnd, result.LyricsStart, result.LyricsEnd, result.Message)
+
+	if !result.Changed || opts.DryRun {
+		return result, nil
+	}
+
+	if source == "embedded" {
+		err = EmbedLyricsOnlyUniversal(audioPath, aligned)
+	} else {
+		err = os.WriteFile(source, []byte(aligned), 0644)
+	}
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to save aligned lyrics: %v", err)
+		return result, err
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+func AlignLyricsFolder(dirPath string, opts LyricsAlignOptions) (*LyricsAlignBatchResult, error) {
+	files, err := ListAudioFiles(dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &LyricsAlignBatchResult{}
+	for _, file := range files {
+		result, err := AlignLyricsFile(file.Path, opts)
+		batch.Results = append(batch.Results, result)
+
+		switch {
+		case err != nil:
+			batch.Failed++
+		case result.Changed:
+			batch.Aligned++
+		default:
+			batch.Skipped++
+		}
+	}
+
+	return batch, nil
+}
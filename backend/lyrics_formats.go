@@ -0,0 +1,255 @@
+package backend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	LyricsFormatLRC         = "lrc"
+	LyricsFormatEnhancedLRC = "enhanced_lrc"
+	LyricsFormatSRT         = "srt"
+	LyricsFormatTTML        = "ttml"
+
+	lastLyricsLineDurationMs = 5000
+)
+
+var enhancedLRCTagRegex = regexp.MustCompile(`<(\d+:\d+(?:\.\d+)?)>`)
+
+type LyricsSyllable struct {
+	StartTimeMs string `json:"startTimeMs"`
+	Text        string `json:"text"`
+	EndTimeMs   string `json:"endTimeMs,omitempty"`
+}
+
+func parseEnhancedLRCWords(text string) (string, []LyricsSyllable) {
+	matches := enhancedLRCTagRegex.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text, nil
+	}
+
+	var syllables []LyricsSyllable
+	var plain strings.Builder
+	plain.WriteString(text[:matches[0][0]])
+
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		segment := text[m[1]:end]
+		plain.WriteString(segment)
+
+		if strings.TrimSpace(segment) == "" {
+			continue
+		}
+
+		start := lrcTimestampToMs(text[m[2]:m[3]])
+		syllable := LyricsSyllable{
+			StartTimeMs: strconv.FormatInt(start, 10),
+			Text:        segment,
+		}
+		if i+1 < len(matches) {
+			syllable.EndTimeMs = strconv.FormatInt(lrcTimestampToMs(text[matches[i+1][2]:matches[i+1][3]]), 10)
+		}
+		syllables = append(syllables, syllable)
+	}
+
+	return strings.Join(strings.Fields(plain.String()), " "), syllables
+}
+
+func lyricsLineTimes(lyrics *LyricsResponse) ([]int64, []int64, bool) {
+	starts := make([]int64, len(lyrics.Lines))
+	ends := make([]int64, len(lyrics.Lines))
+
+	for i, line := range lyrics.Lines {
+		if line.StartTimeMs == "" {
+			return nil, nil, false
+		}
+		starts[i], _ = strconv.ParseInt(line.StartTimeMs, 10, 64)
+	}
+
+	for i, line := range lyrics.Lines {
+		if end, err := strconv.ParseInt(line.EndTimeMs, 10, 64); err == nil && end > starts[i] {
+			ends[i] = end
+		} else if i+1 < len(starts) && starts[i+1] > starts[i] {
+			ends[i] = starts[i+1]
+		} else {
+			ends[i] = starts[i] + lastLyricsLineDurationMs
+		}
+	}
+
+	return starts, ends, true
+}
+
+func formatLRCTime(ms int64) string {
+	return fmt.Sprintf("%02d:%02d.%02d", ms/60000, (ms/1000)%60, (ms%1000)/10)
+}
+
+func formatSRTTime(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+func formatTTMLTime(ms int64) string {
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, (ms/60000)%60, (ms/1000)%60, ms%1000)
+}
+
+func escapeXML(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+func (c *LyricsClient) ConvertToEnhancedLRC(lyrics *LyricsResponse, trackName, artistName string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("[ti:%s]\n", trackName))
+	sb.WriteString(fmt.Sprintf("[ar:%s]\n", artistName))
+	sb.WriteString("[by:SpotiFlac]\n")
+	sb.WriteString("\n")
+
+	for _, line := range lyrics.Lines {
+		if line.Words == "" {
+			continue
+		}
+
+		if line.StartTimeMs == "" {
+			sb.WriteString(fmt.Sprintf("%s\n", line.Words))
+			continue
+		}
+
+		sb.WriteString(msToLRCTimestamp(line.StartTimeMs))
+		if len(line.Syllables) == 0 {
+			sb.WriteString(fmt.Sprintf("%s\n", line.Words))
+			continue
+		}
+
+		for _, syllable := range line.Syllables {
+			start, _ := strconv.ParseInt(syllable.StartTimeMs, 10, 64)
+			sb.WriteString(fmt.Sprintf("<%s>%s", formatLRCTime(start), syllable.Text))
+		}
+		if last := line.Syllables[len(line.Syllables)-1]; last.EndTimeMs != "" {
+			end, _ := strconv.ParseInt(last.EndTimeMs, 10, 64)
+			sb.WriteString(fmt.Sprintf("<%s>", formatLRCTime(end)))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func (c *LyricsClient) ConvertToSRT(lyrics *LyricsResponse) (string, error) {
+	starts, ends, synced := lyricsLineTimes(lyrics)
+	if !synced {
+		return "", fmt.Errorf("lyrics are not time-synced")
+	}
+
+	var sb strings.Builder
+	index := 1
+	for i, line := range lyrics.Lines {
+		if line.Words == "" {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("%d\n%s --> %s\n%s\n\n", index, formatSRTTime(starts[i]), formatSRTTime(ends[i]), line.Words))
+		index++
+	}
+
+	return sb.String(), nil
+}
+
+func (c *LyricsClient) ConvertToTTML(lyrics *LyricsResponse, trackName, artistName string) string {
+	starts, ends, synced := lyricsLineTimes(lyrics)
+
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<tt xmlns=\"http://www.w3.org/ns/ttml\" xmlns:ttm=\"http://www.w3.org/ns/ttml#metadata\">\n")
+	sb.WriteString("  <head>\n    <metadata>\n")
+	sb.WriteString(fmt.Sprintf("      <ttm:title>%s</ttm:title>\n", escapeXML(trackName)))
+	sb.WriteString(fmt.Sprintf("      <ttm:desc>%s</ttm:desc>\n", escapeXML(artistName)))
+	sb.WriteString("    </metadata>\n  </head>\n")
+	sb.WriteString("  <body>\n    <div>\n")
+
+	for i, line := range lyrics.Lines {
+		if line.Words == "" {
+			continue
+		}
+
+		if !synced {
+			sb.WriteString(fmt.Sprintf("      <p>%s</p>\n", escapeXML(line.Words)))
+			continue
+		}
+
+		sb.WriteString(fmt.Sprintf("      <p begin=\"%s\" end=\"%s\">", formatTTMLTime(starts[i]), formatTTMLTime(ends[i])))
+		if len(line.Syllables) == 0 {
+			sb.WriteString(escapeXML(line.Words))
+		} else {
+			for j, syllable := range line.Syllables {
+				start, _ := strconv.ParseInt(syllable.StartTimeMs, 10, 64)
+				end := ends[i]
+				if syllable.EndTimeMs != "" {
+					end, _ = strconv.ParseInt(syllable.EndTimeMs, 10, 64)
+				} else if j+1 < len(line.Syllables) {
+					end, _ = strconv.ParseInt(line.Syllables[j+1].StartTimeMs, 10, 64)
+				}
+				sb.WriteString(fmt.Sprintf("<span begin=\"%s\" end=\"%s\">%s</span>", formatTTMLTime(start), formatTTMLTime(end), escapeXML(syllable.Text)))
+			}
+		}
+		sb.WriteString("</p>\n")
+	}
+
+	sb.WriteString("    </div>\n  </body>\n</tt>\n")
+	return sb.String()
+}
+
+func lyricsFileExtension(format string) string {
+	switch strings.ToLower(format) {
+	case LyricsFormatSRT:
+		return ".srt"
+	case LyricsFormatTTML:
+		return ".ttml"
+	default:
+		return ".lrc"
+	}
+}
+
+func (c *LyricsClient) FormatLyrics(lyrics *LyricsResponse, format, trackName, artistName string) (string, error) {
+	switch strings.ToLower(format) {
+	case "", LyricsFormatLRC:
+		return c.ConvertToLRC(lyrics, trackName, artistName), nil
+	case LyricsFormatEnhancedLRC:
+		return c.ConvertToEnhancedLRC(lyrics, trackName, artistName), nil
+	case LyricsFormatSRT:
+		return c.ConvertToSRT(lyrics)
+	case LyricsFormatTTML:
+		return c.ConvertToTTML(lyrics, trackName, artistName), nil
+	default:
+		return "", fmt.Errorf("unsupported lyrics format: %s", format)
+	}
+}
+
+func (c *LyricsClient) SaveLyricsSidecar(audioPath string, lyrics *LyricsResponse, req TrackRequest, format string) (string, error) {
+	content, err := c.FormatLyrics(lyrics, format, req.Tags.Title, req.Tags.Artist)
+	if err != nil {
+		return "", err
+	}
+
+	position := req.Position
+	if req.UseAlbumTrackNumber && req.Tags.TrackNumber > 0 {
+		position = req.Tags.TrackNumber
+	}
+
+	filename := buildLyricsFilename(req.Tags.Title, req.Tags.Artist, req.Tags.Album, req.Tags.AlbumArtist, req.Tags.ReleaseDate, req.FilenameFormat, req.IncludeTrackNumber, position, req.Tags.DiscNumber)
+	filename = strings.TrimSuffix(filename, ".lrc") + lyricsFileExtension(format)
+	sidecarPath := filepath.Join(filepath.Dir(audioPath), filename)
+
+	if err := os.WriteFile(sidecarPath, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write lyrics file: %w", err)
+	}
+
+	return sidecarPath, nil
+}
@@ -0,0 +1,163 @@
+package backend
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const neteaseMaxLyricFetches = 3
+
+type neteaseSearchResponse struct {
+	Code   int `json:"code"`
+	Result struct {
+		Songs []neteaseSong `json:"songs"`
+	} `json:"result"`
+}
+
+type neteaseSong struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Artists []struct {
+		Name string `json:"name"`
+	} `json:"artists"`
+	Album struct {
+		Name string `json:"name"`
+	} `json:"album"`
+	Duration int64 `json:"duration"`
+}
+
+type neteaseLyricResponse struct {
+	Code        int  `json:"code"`
+	NoLyric     bool `json:"nolyric"`
+	Uncollected bool `json:"uncollected"`
+	Lrc         struct {
+		Lyric string `json:"lyric"`
+	} `json:"lrc"`
+}
+
+func (c *LyricsClient) neteaseGet(apiURL string, target interface{}) error {
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	referer, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9tdXNpYy4xNjMuY29tLw==")
+	req.Header.Set("Referer", string(referer))
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read failed: %v", err)
+	}
+
+	if err := json.Unmarshal(body, target); err != nil {
+		return fmt.Errorf("parse failed: %v", err)
+	}
+	return nil
+}
+
+func (c *LyricsClient) searchNetEase(trackName, artistName string) ([]neteaseSong, error) {
+	query := fmt.Sprintf("%s %s", artistName, trackName)
+	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9tdXNpYy4xNjMuY29tL2FwaS9zZWFyY2gvZ2V0P3R5cGU9MSZsaW1pdD0xMCZzPQ==")
+
+	var result neteaseSearchResponse
+	if err := c.neteaseGet(string(apiBase)+url.QueryEscape(query), &result); err != nil {
+		return nil, err
+	}
+	if result.Code != 200 {
+		return nil, fmt.Errorf("search returned code %d", result.Code)
+	}
+	if len(result.Result.Songs) == 0 {
+		return nil, fmt.Errorf("no results found")
+	}
+
+	return result.Result.Songs, nil
+}
+
+func (c *LyricsClient) fetchNetEaseLyrics(songID int64) (string, error) {
+	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9tdXNpYy4xNjMuY29tL2FwaS9zb25nL2x5cmljP2x2PTEmaWQ9")
+
+	var result neteaseLyricResponse
+	if err := c.neteaseGet(fmt.Sprintf("%s%d", string(apiBase), songID), &result); err != nil {
+		return "", err
+	}
+	if result.Code != 200 {
+		return "", fmt.Errorf("lyrics returned code %d", result.Code)
+	}
+	if result.NoLyric || result.Uncollected {
+		return "", nil
+	}
+
+	return result.Lrc.Lyric, nil
+}
+
+type neteaseLyricsProvider struct{}
+
+func (neteaseLyricsProvider) Name() string { return "NetEase" }
+
+func (neteaseLyricsProvider) Search(client *LyricsClient, q LyricsQuery) ([]LyricsCandidate, error) {
+	songs, err := client.searchNetEase(q.TrackName, q.ArtistName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(songs) > maxSearchCandidates {
+		songs = songs[:maxSearchCandidates]
+	}
+
+	var candidates []LyricsCandidate
+	for _, song := range songs {
+		artists := make([]string, 0, len(song.Artists))
+		for _, artist := range song.Artists {
+			artists = append(artists, artist.Name)
+		}
+
+		candidate := LyricsCandidate{
+			Source:     "NetEase",
+			TrackName:  song.Name,
+			ArtistName: strings.Join(artists, ", "),
+			AlbumName:  song.Album.Name,
+			Duration:   float64(song.Duration) / 1000,
+		}
+
+		if score, _ := ScoreLyricsCandidate(candidate, q); score < minLyricsMatchScore {
+			continue
+		}
+
+		lyrics, err := client.fetchNetEaseLyrics(song.ID)
+		if err != nil {
+			fmt.Printf("   NetEase lyrics for %d: %v\n", song.ID, err)
+			continue
+		}
+		if strings.TrimSpace(lyrics) == "" {
+			continue
+		}
+
+		_, body := splitLRCMetadata(lyrics)
+		candidate.Lyrics = client.convertLRCLibToLyricsResponse(&LRCLibResponse{SyncedLyrics: body})
+		if hasUntimedLines(candidate.Lyrics) {
+			candidate.Lyrics.SyncType = "UNSYNCED"
+		}
+
+		candidates = append(candidates, candidate)
+		if len(candidates) >= neteaseMaxLyricFetches {
+			break
+		}
+	}
+
+	return candidates, nil
+}
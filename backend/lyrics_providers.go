@@ -0,0 +1,406 @@
+package backend
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	minLyricsMatchScore   = 0.6
+	maxLyricsDurationDiff = 10.0
+	maxSearchCandidates   = 10
+)
+
+type LyricsQuery struct {
+	SpotifyID  string `json:"spotify_id,omitempty"`
+	TrackName  string `json:"track_name"`
+	ArtistName string `json:"artist_name"`
+	AlbumName  string `json:"album_name,omitempty"`
+	Duration   int    `json:"duration,omitempty"`
+	Dir        string `json:"dir,omitempty"`
+}
+
+type LyricsCandidate struct {
+	Source     string          `json:"source"`
+	TrackName  string          `json:"track_name"`
+	ArtistName string          `json:"artist_name"`
+	AlbumName  string          `json:"album_name,omitempty"`
+	Duration   float64         `json:"duration,omitempty"`
+	SyncType   string          `json:"sync_type"`
+	LineCount  int             `json:"line_count"`
+	Score      float64         `json:"score"`
+	Accepted   bool            `json:"accepted"`
+	Reasons    []string        `json:"reasons,omitempty"`
+	Lyrics     *LyricsResponse `json:"-"`
+}
+
+type LyricsSearchResult struct {
+	Best       *LyricsCandidate  `json:"best,omitempty"`
+	Candidates []LyricsCandidate `json:"candidates"`
+}
+
+type LyricsProvider interface {
+	Name() string
+	Search(client *LyricsClient, q LyricsQuery) ([]LyricsCandidate, error)
+}
+
+type rankedLyricsProvider struct {
+	provider LyricsProvider
+	rank     int
+}
+
+var (
+	lyricsProviders     []rankedLyricsProvider
+	lyricsProvidersLock sync.RWMutex
+)
+
+func init() {
+	RegisterLyricsProvider(localLyricsProvider{}, 0)
+	RegisterLyricsProvider(lrcLibExactProvider{}, 10)
+	RegisterLyricsProvider(lrcLibSearchProvider{}, 20)
+	RegisterLyricsProvider(neteaseLyricsProvider{}, 30)
+}
+
+func RegisterLyricsProvider(provider LyricsProvider, rank int) {
+	lyricsProvidersLock.Lock()
+	defer lyricsProvidersLock.Unlock()
+
+	for i, p := range lyricsProviders {
+		if p.provider.Name() == provider.Name() {
+			lyricsProviders = append(lyricsProviders[:i], lyricsProviders[i+1:]...)
+			break
+		}
+	}
+
+	lyricsProviders = append(lyricsProviders, rankedLyricsProvider{provider: provider, rank: rank})
+	sort.SliceStable(lyricsProviders, func(i, j int) bool {
+		return lyricsProviders[i].rank < lyricsProviders[j].rank
+	})
+}
+
+func ListLyricsProviders() []string {
+	lyricsProvidersLock.RLock()
+	defer lyricsProvidersLock.RUnlock()
+
+	names := make([]string, 0, len(lyricsProviders))
+	for _, p := range lyricsProviders {
+		names = append(names, p.provider.Name())
+	}
+	return names
+}
+
+func (c *LyricsClient) SearchLyrics(q LyricsQuery) (*LyricsSearchResult, error) {
+	lyricsProvidersLock.RLock()
+	providers := make([]LyricsProvider, 0, len(lyricsProviders))
+	for _, p := range lyricsProviders {
+		providers = append(providers, p.provider)
+	}
+	lyricsProvidersLock.RUnlock()
+
+	result := &LyricsSearchResult{}
+
+	queries := []LyricsQuery{q}
+	if simplified := simplifyTrackName(q.TrackName); simplified != q.TrackName {
+		simplifiedQuery := q
+		simplifiedQuery.TrackName = simplified
+		queries = append(queries, simplifiedQuery)
+	}
+
+	for _, query := range queries {
+		for _, provider := range providers {
+			candidates, err := provider.Search(c, query)
+			if err != nil {
+				fmt.Printf("   %s: %v\n", provider.Name(), err)
+				continue
+			}
+
+			for _, candidate := range candidates {
+				if candidate.Lyrics == nil || candidate.Lyrics.Error || len(candidate.Lyrics.Lines) == 0 {
+					continue
+				}
+				candidate.SyncType = candidate.Lyrics.SyncType
+				candidate.LineCount = len(candidate.Lyrics.Lines)
+				candidate.Score, candidate.Reasons = ScoreLyricsCandidate(candidate, q)
+				candidate.Accepted = candidate.Score >= minLyricsMatchScore
+				result.Candidates = append(result.Candidates, candidate)
+			}
+		}
+
+		if hasAcceptedCandidate(result.Candidates) {
+			break
+		}
+	}
+
+	sort.SliceStable(result.Candidates, func(i, j int) bool {
+		return result.Candidates[i].Score > result.Candidates[j].Score
+	})
+
+	if len(result.Candidates) > 0 && result.Candidates[0].Accepted {
+		result.Best = &result.Candidates[0]
+		return result, nil
+	}
+
+	if len(result.Candidates) > 0 {
+		return result, fmt.Errorf("no lyrics matched the track closely enough (best score %.2f from %s)", result.Candidates[0].Score, result.Candidates[0].Source)
+	}
+	return result, fmt.Errorf("lyrics not found in any source")
+}
+
+func hasAcceptedCandidate(candidates []LyricsCandidate) bool {
+	for _, c := range candidates {
+		if c.Accepted {
+			return true
+		}
+	}
+	return false
+}
+
+func ScoreLyricsCandidate(candidate LyricsCandidate, q LyricsQuery) (float64, []string) {
+	var reasons []string
+
+	durationScore := 0.5
+	if q.Duration > 0 && candidate.Duration > 0 {
+		diff := math.Abs(candidate.Duration - float64(q.Duration))
+		switch {
+		case diff <= 2:
+			durationScore = 1
+		case diff <= 5:
+			durationScore = 0.7
+		case diff <= maxLyricsDurationDiff:
+			durationScore = 0.3
+		default:
+			return 0, []string{fmt.Sprintf("duration differs by %.0fs", diff)}
+		}
+		if diff > 2 {
+			reasons = append(reasons, fmt.Sprintf("duration differs by %.0fs", diff))
+		}
+	}
+
+	artistScore := nameSimilarity(candidate.ArtistName, q.ArtistName)
+	if artistScore < 0.5 {
+		reasons = append(reasons, fmt.Sprintf("artist %q does not match %q", candidate.ArtistName, q.ArtistName))
+	}
+
+	titleScore := math.Max(nameSimilarity(candidate.TrackName, q.TrackName), nameSimilarity(candidate.TrackName, simplifyTrackName(q.TrackName)))
+	if titleScore < 0.5 {
+		reasons = append(reasons, fmt.Sprintf("title %q does not match %q", candidate.TrackName, q.TrackName))
+	}
+
+	albumScore := 0.5
+	if q.AlbumName != "" && candidate.AlbumName != "" {
+		albumScore = nameSimilarity(candidate.AlbumName, q.AlbumName)
+		if albumScore < 0.5 {
+			reasons = append(reasons, fmt.Sprintf("album %q does not match %q", candidate.AlbumName, q.AlbumName))
+		}
+	}
+
+	score := 0.4*durationScore + 0.3*artistScore + 0.2*titleScore + 0.1*albumScore
+
+	switch candidate.SyncType {
+	case "SYLLABLE_SYNCED":
+		score += 0.07
+	case "LINE_SYNCED":
+		score += 0.05
+	}
+
+	return math.Min(1, score), reasons
+}
+
+func normalizeForMatch(s string) []string {
+	s = strings.ToLower(s)
+	for _, marker := range []string{" feat.", " feat ", " ft.", " featuring "} {
+		if idx := strings.Index(s, marker); idx > 0 {
+			s = s[:idx]
+		}
+	}
+
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+func nameSimilarity(a, b string) float64 {
+	ta, tb := normalizeForMatch(a), normalizeForMatch(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	ja, jb := strings.Join(ta, " "), strings.Join(tb, " ")
+	if ja == jb {
+		return 1
+	}
+	if strings.Contains(ja, jb) || strings.Contains(jb, ja) {
+		return 0.9
+	}
+
+	set := make(map[string]bool, len(ta))
+	for _, t := range ta {
+		set[t] = true
+	}
+	common := 0
+	union := len(set)
+	for _, t := range tb {
+		if set[t] {
+			common++
+			delete(set, t)
+		} else {
+			union++
+		}
+	}
+
+	return float64(common) / float64(union)
+}
+
+type localLyricsProvider struct{}
+
+func (localLyricsProvider) Name() string { return "Local" }
+
+func (localLyricsProvider) Search(client *LyricsClient, q LyricsQuery) ([]LyricsCandidate, error) {
+	if q.Dir == "" {
+		return nil, nil
+	}
+
+	var candidates []LyricsCandidate
+	for _, path := range findLocalLyricsFiles(q.Dir, q.TrackName, q.ArtistName) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		content := string(data)
+		if parseLRCOffsetTag(content) != 0 {
+			content = rewriteLRCTimestamps(content, alignTransform{scale: 1})
+		}
+
+		tags, body := splitLRCMetadata(content)
+		candidate := LyricsCandidate{
+			Source:     "Local (" + filepath.Base(path) + ")",
+			TrackName:  q.TrackName,
+			ArtistName: q.ArtistName,
+			AlbumName:  tags["al"],
+			Lyrics:     client.convertLRCLibToLyricsResponse(&LRCLibResponse{SyncedLyrics: body}),
+		}
+		if title := tags["ti"]; title != "" {
+			candidate.TrackName = title
+		}
+		if artist := tags["ar"]; artist != "" {
+			candidate.ArtistName = artist
+		}
+		if length := tags["length"]; length != "" {
+			candidate.Duration = float64(lrcTimestampToMs(length)) / 1000
+		}
+		if hasUntimedLines(candidate.Lyrics) {
+			candidate.Lyrics.SyncType = "UNSYNCED"
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	return candidates, nil
+}
+
+func findLocalLyricsFiles(dir, trackName, artistName string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if seen[path] {
+			return
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Size() > 0 {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+
+	if audioFile := findAudioFileForLyrics(dir, trackName, artistName); audioFile != "" {
+		add(strings.TrimSuffix(audioFile, filepath.Ext(audioFile)) + ".lrc")
+	}
+
+	for _, format := range []string{"title-artist", "artist-title", "title"} {
+		add(filepath.Join(dir, buildLyricsFilename(trackName, artistName, "", "", "", format, false, 0, 0)))
+	}
+
+	return paths
+}
+
+func splitLRCMetadata(content string) (map[string]string, string) {
+	tags := make(map[string]string)
+	var body []string
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			inner := trimmed[1 : len(trimmed)-1]
+			if colon := strings.Index(inner, ":"); colon > 0 {
+				key := strings.ToLower(inner[:colon])
+				if key != "" && unicode.IsLetter(rune(key[0])) {
+					tags[key] = strings.TrimSpace(inner[colon+1:])
+					continue
+				}
+			}
+		}
+		body = append(body, line)
+	}
+
+	return tags, strings.Join(body, "\n")
+}
+
+func hasUntimedLines(lyrics *LyricsResponse) bool {
+	for _, line := range lyrics.Lines {
+		if line.StartTimeMs == "" {
+			return true
+		}
+	}
+	return false
+}
+
+type lrcLibExactProvider struct{}
+
+func (lrcLibExactProvider) Name() string { return "LRCLIB" }
+
+func (lrcLibExactProvider) Search(client *LyricsClient, q LyricsQuery) ([]LyricsCandidate, error) {
+	resp, err := client.fetchLRCLibExact(q.TrackName, q.ArtistName, q.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	return []LyricsCandidate{lrcLibCandidate(client, "LRCLIB", resp)}, nil
+}
+
+type lrcLibSearchProvider struct{}
+
+func (lrcLibSearchProvider) Name() string { return "LRCLIB Search" }
+
+func (lrcLibSearchProvider) Search(client *LyricsClient, q LyricsQuery) ([]LyricsCandidate, error) {
+	results, err := client.searchLRCLib(q.TrackName, q.ArtistName)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) > maxSearchCandidates {
+		results = results[:maxSearchCandidates]
+	}
+
+	candidates := make([]LyricsCandidate, 0, len(results))
+	for i := range results {
+		candidates = append(candidates, lrcLibCandidate(client, "LRCLIB Search", &results[i]))
+	}
+	return candidates, nil
+}
+
+func lrcLibCandidate(client *LyricsClient, source string, resp *LRCLibResponse) LyricsCandidate {
+	return LyricsCandidate{
+		Source:     source,
+		TrackName:  resp.TrackName,
+		ArtistName: resp.ArtistName,
+		AlbumName:  resp.AlbumName,
+		Duration:   resp.Duration,
+		Lyrics:     client.convertLRCLibToLyricsResponse(resp),
+	}
+}
@@ -31,6 +31,29 @@ type Metadata struct {
 	Publisher   string
 	Lyrics      string
 	Description string
+	Genre       string
+	Composer    string
+	BPM         int
+	ISRC        string
+	ReplayGain  *ReplayGainTags
+
+	SpotifyID    string
+	TidalTrackID string
+	QobuzTrackID string
+
+	MusicBrainzTrackID        string
+	MusicBrainzAlbumID        string
+	MusicBrainzArtistID       string
+	MusicBrainzAlbumArtistID  string
+	MusicBrainzReleaseGroupID string
+}
+
+type ReplayGainTags struct {
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+	HasAlbum  bool
 }
 
 func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
@@ -39,62 +62,8 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 		return fmt.Errorf("failed to parse FLAC file: %w", err)
 	}
 
-	var cmtIdx = -1
-	for idx, block := range f.Meta {
-		if block.Type == flac.VorbisComment {
-			cmtIdx = idx
-			break
-		}
-	}
-
-	cmt := flacvorbis.New()
-
-	if metadata.Title != "" {
-		_ = cmt.Add(flacvorbis.FIELD_TITLE, metadata.Title)
-	}
-	if metadata.Artist != "" {
-		_ = cmt.Add(flacvorbis.FIELD_ARTIST, metadata.Artist)
-	}
-	if metadata.Album != "" {
-		_ = cmt.Add(flacvorbis.FIELD_ALBUM, metadata.Album)
-	}
-	if metadata.AlbumArtist != "" {
-		_ = cmt.Add("ALBUMARTIST", metadata.AlbumArtist)
-	}
-	if metadata.Date != "" {
-		_ = cmt.Add(flacvorbis.FIELD_DATE, metadata.Date)
-	}
-	if metadata.TrackNumber > 0 {
-		_ = cmt.Add(flacvorbis.FIELD_TRACKNUMBER, strconv.Itoa(metadata.TrackNumber))
-	}
-	if metadata.TotalTracks > 0 {
-		_ = cmt.Add("TOTALTRACKS", strconv.Itoa(metadata.TotalTracks))
-	}
-	if metadata.DiscNumber > 0 {
-		_ = cmt.Add("DISCNUMBER", strconv.Itoa(metadata.DiscNumber))
-	}
-	if metadata.TotalDiscs > 0 {
-		_ = cmt.Add("TOTALDISCS", strconv.Itoa(metadata.TotalDiscs))
-	}
-	if metadata.Copyright != "" {
-		_ = cmt.Add("COPYRIGHT", metadata.Copyright)
-	}
-	if metadata.Publisher != "" {
-		_ = cmt.Add("PUBLISHER", metadata.Publisher)
-	}
-	if metadata.Description != "" {
-		_ = cmt.Add("DESCRIPTION", metadata.Description)
-	}
-
-	if metadata.Lyrics != "" {
-		_ = cmt.Add("LYRICS", metadata.Lyrics)
-	}
-
-	cmtBlock := cmt.Marshal()
-	if cmtIdx < 0 {
-		f.Meta = append(f.Meta, &cmtBlock)
-	} else {
-		f.Meta[cmtIdx] = &cmtBlock
+	if err := setFLACComments(f, metadata); err != nil {
+		return err
 	}
 
 	if coverPath != "" && fileExists(coverPath) {
@@ -110,6 +79,21 @@ func EmbedMetadata(filepath string, metadata Metadata, coverPath string) error {
 	return nil
 }
 
+func EmbedReplayGain(filepath string, rg *ReplayGainTags) error {
+	store, err := TagStoreFor(filepath)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := store.Read(filepath)
+	if err != nil {
+		return err
+	}
+	metadata.ReplayGain = rg
+
+	return store.Write(filepath, metadata)
+}
+
 func embedCoverArt(f *flac.File, coverPath string) error {
 	imgData, err := os.ReadFile(coverPath)
 	if err != nil {
@@ -284,7 +268,23 @@ func extractCoverFromM4AOrFlac(filePath string) (string, error) {
 		return "", fmt.Errorf("no cover art found")
 	}
 
-	return "", nil
+	cover, err := readMP4Cover(filePath)
+	if err != nil {
+		return "", err
+	}
+
+	tmpFile, err := os.CreateTemp("", "cover-*.jpg")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer tmpFile.Close()
+
+	if _, err := tmpFile.Write(cover); err != nil {
+		os.Remove(tmpFile.Name())
+		return "", fmt.Errorf("failed to write cover art: %w", err)
+	}
+
+	return tmpFile.Name(), nil
 }
 
 func ExtractLyrics(filePath string) (string, error) {
@@ -296,8 +296,11 @@ func ExtractLyrics(filePath string) (string, error) {
 	case ".flac":
 		return extractLyricsFromFlac(filePath)
 	case ".m4a":
-
-		return "", nil
+		metadata, _, err := readMP4Tags(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read M4A file: %w", err)
+		}
+		return metadata.Lyrics, nil
 	default:
 		return "", fmt.Errorf("unsupported file format: %s", ext)
 	}
@@ -373,13 +376,26 @@ func EmbedCoverArtOnly(filePath string, coverPath string) error {
 	case ".mp3":
 		return embedCoverToMp3(filePath, coverPath)
 	case ".m4a":
-
-		return nil
+		return embedCoverToM4A(filePath, coverPath)
 	default:
 		return fmt.Errorf("unsupported file format: %s", ext)
 	}
 }
 
+func embedCoverToM4A(filePath string, coverPath string) error {
+	artwork, err := os.ReadFile(coverPath)
+	if err != nil {
+		return fmt.Errorf("failed to read cover art: %w", err)
+	}
+
+	metadata, _, err := readMP4Tags(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read M4A file: %w", err)
+	}
+
+	return writeMP4Tags(filePath, metadata, artwork)
+}
+
 func embedCoverToMp3(filePath string, coverPath string) error {
 	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
 	if err != nil {
@@ -454,48 +470,17 @@ func embedLyricsToM4A(filepath string, lyrics string) error {
 	}
 	lyrics = validatedLyrics
 
-	ffmpegPath, err := GetFFmpegPath()
+	metadata, _, err := readMP4Tags(filepath)
 	if err != nil {
-		return fmt.Errorf("ffmpeg not found: %w", err)
-	}
-
-	if err := ValidateExecutable(ffmpegPath); err != nil {
-		return fmt.Errorf("invalid ffmpeg executable: %w", err)
+		return fmt.Errorf("failed to read M4A tags: %w", err)
 	}
+	metadata.Lyrics = lyrics
 
-	tmpOutputFile := strings.TrimSuffix(filepath, pathfilepath.Ext(filepath)) + ".tmp" + pathfilepath.Ext(filepath)
-	defer func() {
-
-		if _, err := os.Stat(tmpOutputFile); err == nil {
-			os.Remove(tmpOutputFile)
-		}
-	}()
-
-	cmd := exec.Command(ffmpegPath,
-		"-i", filepath,
-		"-map", "0",
-		"-map_metadata", "0",
-		"-metadata", "lyrics-eng="+lyrics,
-		"-metadata", "lyrics="+lyrics,
-		"-codec", "copy",
-		"-f", "ipod",
-		"-y",
-		tmpOutputFile,
-	)
-
-	setHideWindow(cmd)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		fmt.Printf("[FFmpeg] Error embedding lyrics to M4A: %s\n", string(output))
-		return fmt.Errorf("ffmpeg failed to embed lyrics: %s - %w", string(output), err)
+	if err := writeMP4Tags(filepath, metadata, nil); err != nil {
+		return fmt.Errorf("failed to embed lyrics: %w", err)
 	}
 
-	if err := os.Rename(tmpOutputFile, filepath); err != nil {
-		return fmt.Errorf("failed to replace original file: %w", err)
-	}
-
-	fmt.Printf("[FFmpeg] Lyrics embedded to M4A successfully: %d characters\n", len(lyrics))
+	fmt.Printf("[M4A] Lyrics embedded to M4A successfully: %d characters\n", len(lyrics))
 	return nil
 }
 
@@ -520,11 +505,17 @@ func EmbedLyricsOnlyUniversal(filepath string, lyrics string) error {
 func GetAudioDuration(filepath string) (float64, error) {
 	ext := strings.ToLower(pathfilepath.Ext(filepath))
 
-	if ext == ".flac" {
+	switch ext {
+	case ".flac":
 		duration, err := getFlacDuration(filepath)
 		if err == nil && duration > 0 {
 			return duration, nil
 		}
+	case ".m4a", ".mp4":
+		duration, err := getMP4Duration(filepath)
+		if err == nil && duration > 0 {
+			return duration, nil
+		}
 	}
 
 	return getDurationWithFFprobe(filepath)
@@ -676,108 +667,19 @@ func parseLRCTimestamp(timestamp string) int64 {
 }
 
 func ExtractFullMetadataFromFile(filePath string) (Metadata, error) {
-	var metadata Metadata
-
-	ffprobePath, err := GetFFprobePath()
-	if err != nil {
-		return metadata, err
-	}
-
-	if err := ValidateExecutable(ffprobePath); err != nil {
-		return metadata, fmt.Errorf("invalid ffprobe executable: %w", err)
+	if store, err := TagStoreFor(filePath); err == nil {
+		return store.Read(filePath)
 	}
 
-	cmd := exec.Command(ffprobePath,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-		filePath,
-	)
-
-	setHideWindow(cmd)
+	var metadata Metadata
 
-	output, err := cmd.Output()
+	tags, err := probeFormatTags(filePath)
 	if err != nil {
 		return metadata, err
 	}
 
-	var result struct {
-		Format struct {
-			Tags map[string]string `json:"tags"`
-		} `json:"format"`
-		Streams []struct {
-			Tags map[string]string `json:"tags"`
-		} `json:"streams"`
-	}
-
-	if err := json.Unmarshal(output, &result); err != nil {
-		return metadata, err
-	}
-
-	allTags := make(map[string]string)
-
-	for _, stream := range result.Streams {
-		for key, value := range stream.Tags {
-			allTags[strings.ToLower(key)] = value
-		}
-	}
-
-	for key, value := range result.Format.Tags {
-		allTags[strings.ToLower(key)] = value
-	}
-
-	for key, value := range allTags {
-		switch key {
-		case "title":
-			metadata.Title = value
-		case "artist":
-			metadata.Artist = value
-		case "album":
-			metadata.Album = value
-		case "album_artist", "albumartist":
-			metadata.AlbumArtist = value
-		case "date", "year":
-			if metadata.Date == "" || len(value) > len(metadata.Date) {
-				metadata.Date = value
-			}
-		case "track":
-
-			parts := strings.Split(value, "/")
-			if len(parts) > 0 {
-				if num, err := strconv.Atoi(parts[0]); err == nil {
-					metadata.TrackNumber = num
-				}
-			}
-			if len(parts) > 1 {
-				if num, err := strconv.Atoi(parts[1]); err == nil {
-					metadata.TotalTracks = num
-				}
-			}
-		case "disc":
-
-			parts := strings.Split(value, "/")
-			if len(parts) > 0 {
-				if num, err := strconv.Atoi(parts[0]); err == nil {
-					metadata.DiscNumber = num
-				}
-			}
-			if len(parts) > 1 {
-				if num, err := strconv.Atoi(parts[1]); err == nil {
-					metadata.TotalDiscs = num
-				}
-			}
-		case "copyright", "tcop":
-			metadata.Copyright = value
-		case "publisher", "tpub", "label":
-			metadata.Publisher = value
-		case "url":
-			metadata.URL = value
-		case "description", "comment":
-			if metadata.Description == "" {
-				metadata.Description = value
-			}
-		}
+	for key, value := range tags {
+		applyTagField(&metadata, key, value)
 	}
 
 	return metadata, nil
@@ -806,57 +708,7 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 	}
 	defer tag.Close()
 
-	tag.DeleteFrames("TXXX")
-
-	if metadata.Title != "" {
-		tag.SetTitle(metadata.Title)
-	}
-	if metadata.Artist != "" {
-		tag.SetArtist(metadata.Artist)
-	}
-	if metadata.Album != "" {
-		tag.SetAlbum(metadata.Album)
-	}
-	if metadata.Date != "" {
-		year := metadata.Date
-		if len(year) >= 4 {
-			year = year[:4]
-		}
-		tag.SetYear(year)
-	}
-
-	if metadata.AlbumArtist != "" {
-		tag.DeleteFrames("TPE2")
-		tag.AddTextFrame("TPE2", id3v2.EncodingUTF8, metadata.AlbumArtist)
-	}
-
-	if metadata.TrackNumber > 0 {
-		tag.DeleteFrames(tag.CommonID("Track number/Position in set"))
-		trackStr := strconv.Itoa(metadata.TrackNumber)
-		if metadata.TotalTracks > 0 {
-			trackStr = fmt.Sprintf("%d/%d", metadata.TrackNumber, metadata.TotalTracks)
-		}
-		tag.AddTextFrame(tag.CommonID("Track number/Position in set"), id3v2.EncodingUTF8, trackStr)
-	}
-
-	if metadata.DiscNumber > 0 {
-		tag.DeleteFrames(tag.CommonID("Part of a set"))
-		discStr := strconv.Itoa(metadata.DiscNumber)
-		if metadata.TotalDiscs > 0 {
-			discStr = fmt.Sprintf("%d/%d", metadata.DiscNumber, metadata.TotalDiscs)
-		}
-		tag.AddTextFrame(tag.CommonID("Part of a set"), id3v2.EncodingUTF8, discStr)
-	}
-
-	if metadata.Copyright != "" {
-		tag.DeleteFrames("TCOP")
-		tag.AddTextFrame("TCOP", id3v2.EncodingUTF8, metadata.Copyright)
-	}
-
-	if metadata.Publisher != "" {
-		tag.DeleteFrames("TPUB")
-		tag.AddTextFrame("TPUB", id3v2.EncodingUTF8, metadata.Publisher)
-	}
+	setMP3Frames(tag, metadata)
 
 	if coverPath != "" && fileExists(coverPath) {
 
@@ -885,83 +737,15 @@ func embedMetadataToMP3(filePath string, metadata Metadata, coverPath string) er
 }
 
 func embedMetadataToM4A(filePath string, metadata Metadata, coverPath string) error {
-	ffmpegPath, err := GetFFmpegPath()
-	if err != nil {
-		return fmt.Errorf("ffmpeg not found: %w", err)
-	}
-
-	if err := ValidateExecutable(ffmpegPath); err != nil {
-		return fmt.Errorf("invalid ffmpeg executable: %w", err)
-	}
-
-	args := []string{
-		"-i", filePath,
-		"-y",
-	}
-
+	var cover []byte
 	if coverPath != "" && fileExists(coverPath) {
-		args = append(args, "-i", coverPath)
-		args = append(args, "-map", "0:a", "-map", "1", "-c:a", "copy", "-c:v", "copy", "-disposition:v:0", "attached_pic")
-	} else {
-		args = append(args, "-map", "0", "-codec", "copy")
-	}
-
-	if metadata.Title != "" {
-		args = append(args, "-metadata", "title="+metadata.Title)
-	}
-	if metadata.Artist != "" {
-		args = append(args, "-metadata", "artist="+metadata.Artist)
-	}
-	if metadata.Album != "" {
-		args = append(args, "-metadata", "album="+metadata.Album)
-	}
-	if metadata.AlbumArtist != "" {
-		args = append(args, "-metadata", "album_artist="+metadata.AlbumArtist)
-	}
-	if metadata.Date != "" {
-		args = append(args, "-metadata", "date="+metadata.Date)
-	}
-	if metadata.TrackNumber > 0 {
-		trackStr := strconv.Itoa(metadata.TrackNumber)
-		if metadata.TotalTracks > 0 {
-			trackStr = fmt.Sprintf("%d/%d", metadata.TrackNumber, metadata.TotalTracks)
-		}
-		args = append(args, "-metadata", "track="+trackStr)
-	}
-	if metadata.DiscNumber > 0 {
-		discStr := strconv.Itoa(metadata.DiscNumber)
-		if metadata.TotalDiscs > 0 {
-			discStr = fmt.Sprintf("%d/%d", metadata.DiscNumber, metadata.TotalDiscs)
-		}
-		args = append(args, "-metadata", "disk="+discStr)
-	}
-	if metadata.Copyright != "" {
-		args = append(args, "-metadata", "copyright="+metadata.Copyright)
-	}
-	if metadata.Publisher != "" {
-		args = append(args, "-metadata", "publisher="+metadata.Publisher)
-	}
-
-	tmpOutputFile := strings.TrimSuffix(filePath, pathfilepath.Ext(filePath)) + ".tmp" + pathfilepath.Ext(filePath)
-	defer func() {
-		if _, err := os.Stat(tmpOutputFile); err == nil {
-			os.Remove(tmpOutputFile)
+		artwork, err := os.ReadFile(coverPath)
+		if err != nil {
+			fmt.Printf("[EmbedMetadataToM4A] Warning: Failed to read cover art file: %v\n", err)
+		} else {
+			cover = artwork
 		}
-	}()
-
-	args = append(args, "-f", "ipod", tmpOutputFile)
-
-	cmd := exec.Command(ffmpegPath, args...)
-	setHideWindow(cmd)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("ffmpeg failed to embed metadata: %s - %w", string(output), err)
 	}
 
-	if err := os.Rename(tmpOutputFile, filePath); err != nil {
-		return fmt.Errorf("failed to replace original file: %w", err)
-	}
-
-	return nil
+	return writeMP4Tags(filePath, metadata, cover)
 }
@@ -0,0 +1,739 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	pathfilepath "path/filepath"
+	"strings"
+)
+
+const (
+	mp4FreeformMean = "com.apple.iTunes"
+	mp4DataImplicit = 0
+	mp4DataUTF8     = 1
+	mp4DataJPEG     = 13
+	mp4DataPNG      = 14
+	mp4DataInteger  = 21
+	mp4PaddingSize  = 2048
+)
+
+var mp4ContainerAtoms = map[string]bool{
+	"moov": true,
+	"trak": true,
+	"mdia": true,
+	"minf": true,
+	"stbl": true,
+	"udta": true,
+	"edts": true,
+	"dinf": true,
+	"ilst": true,
+}
+
+var mp4TextAtoms = map[string]string{
+	"\xa9nam": "title",
+	"\xa9ART": "artist",
+	"aART":    "albumartist",
+	"\xa9alb": "album",
+	"\xa9day": "date",
+	"\xa9gen": "genre",
+	"\xa9wrt": "composer",
+	"\xa9lyr": "lyrics",
+	"\xa9cmt": "comment",
+	"cprt":    "copyright",
+}
+
+var id3v1Genres = []string{
+	"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop",
+	"Jazz", "Metal", "New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock",
+	"Techno", "Industrial", "Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack",
+	"Euro-Techno", "Ambient", "Trip-Hop", "Vocal", "Jazz+Funk", "Fusion", "Trance",
+	"Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip", "Gospel", "Noise",
+	"AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop",
+	"Instrumental Rock", "Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic",
+	"Pop-Folk", "Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta",
+	"Top 40", "Christian Rap", "Pop/Funk", "Jungle", "Native American", "Cabaret",
+	"New Wave", "Psychedelic", "Rave", "Showtunes", "Trailer", "Lo-Fi", "Tribal",
+	"Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	"Folk", "Folk-Rock", "National Folk", "Swing", "Fast Fusion", "Bebob", "Latin",
+	"Revival", "Celtic", "Bluegrass", "Avantgarde", "Gothic Rock", "Progressive Rock",
+	"Psychedelic Rock", "Symphonic Rock", "Slow Rock", "Big Band", "Chorus",
+	"Easy Listening", "Acoustic", "Humour", "Speech", "Chanson", "Opera", "Chamber Music",
+	"Sonata", "Symphony", "Booty Bass", "Primus", "Porn Groove", "Satire", "Slow Jam",
+	"Club", "Tango", "Samba", "Folklore", "Ballad", "Power Ballad", "Rhythmic Soul",
+	"Freestyle", "Duet", "Punk Rock", "Drum Solo", "A capella", "Euro-House", "Dance Hall",
+}
+
+type mp4Atom struct {
+	typ       string
+	prefix    []byte
+	data      []byte
+	children  []*mp4Atom
+	trailer   []byte
+	container bool
+}
+
+// Containers may end with bytes that are not an atom, such as the 4-byte zero
+// terminator QuickTime writes at the end of udta. They are returned as the
+// trailer so the container can be rebuilt byte for byte.
+func parseMP4Atoms(buf []byte, inIlst bool) ([]*mp4Atom, []byte, error) {
+	var atoms []*mp4Atom
+
+	for len(buf) > 0 {
+		if len(buf) < 8 {
+			return atoms, buf, nil
+		}
+
+		size := int64(binary.BigEndian.Uint32(buf))
+		typ := string(buf[4:8])
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			if len(atoms) > 0 {
+				return atoms, buf, nil
+			}
+			size = int64(len(buf))
+		case 1:
+			if len(buf) < 16 {
+				return nil, nil, fmt.Errorf("truncated %s atom", typ)
+			}
+			size = int64(binary.BigEndian.Uint64(buf[8:]))
+			headerSize = 16
+		}
+		if size < headerSize || size > int64(len(buf)) {
+			return nil, nil, fmt.Errorf("invalid %s atom size", typ)
+		}
+
+		payload := buf[headerSize:size]
+		atom := &mp4Atom{typ: typ}
+
+		var err error
+		switch {
+		case typ == "meta":
+			atom.container = true
+			if len(payload) >= 8 && string(payload[4:8]) == "hdlr" {
+				atom.children, atom.trailer, err = parseMP4Atoms(payload, false)
+			} else if len(payload) >= 4 {
+				atom.prefix = payload[:4]
+				atom.children, atom.trailer, err = parseMP4Atoms(payload[4:], false)
+			} else {
+				err = fmt.Errorf("truncated meta atom")
+			}
+		case mp4ContainerAtoms[typ] || inIlst:
+			atom.container = true
+			atom.children, atom.trailer, err = parseMP4Atoms(payload, typ == "ilst")
+		default:
+			atom.data = payload
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		atoms = append(atoms, atom)
+		buf = buf[size:]
+	}
+
+	return atoms, nil, nil
+}
+
+func (a *mp4Atom) size() int64 {
+	size := int64(8 + len(a.prefix))
+	if !a.container {
+		return size + int64(len(a.data))
+	}
+	for _, child := range a.children {
+		size += child.size()
+	}
+	return size + int64(len(a.trailer))
+}
+
+func (a *mp4Atom) appendTo(out []byte) ([]byte, error) {
+	size := a.size()
+	if size > 0xffffffff {
+		return nil, fmt.Errorf("%s atom is too large", a.typ)
+	}
+
+	out = binary.BigEndian.AppendUint32(out, uint32(size))
+	out = append(out, a.typ...)
+	out = append(out, a.prefix...)
+	if !a.container {
+		return append(out, a.data...), nil
+	}
+
+	var err error
+	for _, child := range a.children {
+		if out, err = child.appendTo(out); err != nil {
+			return nil, err
+		}
+	}
+	return append(out, a.trailer...), nil
+}
+
+func (a *mp4Atom) child(typ string) *mp4Atom {
+	for _, c := range a.children {
+		if c.typ == typ {
+			return c
+		}
+	}
+	return nil
+}
+
+func (a *mp4Atom) path(types ...string) *mp4Atom {
+	cur := a
+	for _, typ := range types {
+		if cur = cur.child(typ); cur == nil {
+			return nil
+		}
+	}
+	return cur
+}
+
+type mp4TopAtom struct {
+	typ    string
+	offset int64
+	size   int64
+}
+
+func scanMP4TopLevel(f *os.File) ([]mp4TopAtom, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	fileSize := info.Size()
+
+	var atoms []mp4TopAtom
+	header := make([]byte, 16)
+	for offset := int64(0); offset+8 <= fileSize; {
+		if _, err := f.ReadAt(header[:8], offset); err != nil {
+			return nil, err
+		}
+
+		size := int64(binary.BigEndian.Uint32(header))
+		typ := string(header[4:8])
+		switch size {
+		case 0:
+			size = fileSize - offset
+		case 1:
+			if _, err := f.ReadAt(header[8:16], offset+8); err != nil {
+				return nil, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:]))
+		}
+		if size < 8 || offset+size > fileSize {
+			return nil, fmt.Errorf("invalid %s atom at offset %d", typ, offset)
+		}
+
+		atoms = append(atoms, mp4TopAtom{typ: typ, offset: offset, size: size})
+		offset += size
+	}
+
+	if len(atoms) == 0 || atoms[0].typ != "ftyp" {
+		return nil, fmt.Errorf("not an MP4 file")
+	}
+	return atoms, nil
+}
+
+type mp4File struct {
+	file  *os.File
+	atoms []mp4TopAtom
+	index int
+	moov  *mp4Atom
+}
+
+func openMP4File(filePath string, flag int) (*mp4File, error) {
+	f, err := os.OpenFile(filePath, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	atoms, err := scanMP4TopLevel(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	for i, atom := range atoms {
+		if atom.typ != "moov" {
+			continue
+		}
+
+		buf := make([]byte, atom.size)
+		if _, err := f.ReadAt(buf, atom.offset); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read moov atom: %w", err)
+		}
+		parsed, _, err := parseMP4Atoms(buf, false)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to parse moov atom: %w", err)
+		}
+
+		return &mp4File{file: f, atoms: atoms, index: i, moov: parsed[0]}, nil
+	}
+
+	f.Close()
+	return nil, fmt.Errorf("no moov atom found")
+}
+
+func mp4DataPayload(item *mp4Atom) (uint32, []byte) {
+	data := item.child("data")
+	if data == nil || len(data.data) < 8 {
+		return 0, nil
+	}
+	return binary.BigEndian.Uint32(data.data) & 0xffffff, data.data[8:]
+}
+
+func mp4FreeformName(item *mp4Atom) (string, string) {
+	var mean, name string
+	if a := item.child("mean"); a != nil && len(a.data) >= 4 {
+		mean = string(a.data[4:])
+	}
+	if a := item.child("name"); a != nil && len(a.data) >= 4 {
+		name = string(a.data[4:])
+	}
+	return mean, name
+}
+
+func readMP4Tags(filePath string) (Metadata, []byte, error) {
+	var metadata Metadata
+
+	mf, err := openMP4File(filePath, os.O_RDONLY)
+	if err != nil {
+		return metadata, nil, err
+	}
+	defer mf.file.Close()
+
+	ilst := mf.moov.path("udta", "meta", "ilst")
+	if ilst == nil {
+		return metadata, nil, nil
+	}
+
+	var cover []byte
+	var genre string
+	for _, item := range ilst.children {
+		dataType, payload := mp4DataPayload(item)
+
+		switch item.typ {
+		case "----":
+			if mean, name := mp4FreeformName(item); mean == mp4FreeformMean {
+				applyTagField(&metadata, name, string(payload))
+			}
+		case "trkn", "disk":
+			if len(payload) >= 6 {
+				num := int(binary.BigEndian.Uint16(payload[2:]))
+				total := int(binary.BigEndian.Uint16(payload[4:]))
+				if item.typ == "trkn" {
+					metadata.TrackNumber, metadata.TotalTracks = num, total
+				} else {
+					metadata.DiscNumber, metadata.TotalDiscs = num, total
+				}
+			}
+		case "tmpo":
+			if n, ok := mp4Integer(payload); ok && n > 0 {
+				metadata.BPM = n
+			}
+		case "gnre":
+			if n, ok := mp4Integer(payload); ok && n > 0 && n <= len(id3v1Genres) {
+				genre = id3v1Genres[n-1]
+			}
+		case "covr":
+			if cover == nil && len(payload) > 0 {
+				cover = append([]byte(nil), payload...)
+			}
+		default:
+			if key, ok := mp4TextAtoms[item.typ]; ok && dataType == mp4DataUTF8 {
+				applyTagField(&metadata, key, string(payload))
+			}
+		}
+	}
+
+	if metadata.Genre == "" {
+		metadata.Genre = genre
+	}
+
+	return metadata, cover, nil
+}
+
+func mp4Integer(payload []byte) (int, bool) {
+	switch len(payload) {
+	case 1:
+		return int(int8(payload[0])), true
+	case 2:
+		return int(int16(binary.BigEndian.Uint16(payload))), true
+	case 4:
+		return int(int32(binary.BigEndian.Uint32(payload))), true
+	case 8:
+		return int(int64(binary.BigEndian.Uint64(payload))), true
+	}
+	return 0, false
+}
+
+func newMP4DataItem(typ string, dataType uint32, payload []byte) *mp4Atom {
+	data := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(data, dataType)
+	data = append(data, payload...)
+
+	return &mp4Atom{
+		typ:       typ,
+		container: true,
+		children:  []*mp4Atom{{typ: "data", data: data}},
+	}
+}
+
+func newMP4FreeformItem(name, value string) *mp4Atom {
+	item := newMP4DataItem("----", mp4DataUTF8, []byte(value))
+	item.children = append([]*mp4Atom{
+		{typ: "mean", data: append([]byte{0, 0, 0, 0}, mp4FreeformMean...)},
+		{typ: "name", data: append([]byte{0, 0, 0, 0}, name...)},
+	}, item.children...)
+	return item
+}
+
+func mp4NumberPair(num, total int, trailing bool) []byte {
+	payload := make([]byte, 6, 8)
+	binary.BigEndian.PutUint16(payload[2:], uint16(num))
+	binary.BigEndian.PutUint16(payload[4:], uint16(total))
+	if trailing {
+		payload = append(payload, 0, 0)
+	}
+	return payload
+}
+
+func mp4CoverType(cover []byte) uint32 {
+	if bytes.HasPrefix(cover, []byte("\x89PNG")) {
+		return mp4DataPNG
+	}
+	return mp4DataJPEG
+}
+
+func mp4FreeformFields(m Metadata) []tagField {
+	fields := []tagField{
+		{"ISRC", m.ISRC},
+		{"URL", m.URL},
+		{"LABEL", m.Publisher},
+		{"Spotify Track Id", m.SpotifyID},
+		{"Tidal Track Id", m.TidalTrackID},
+		{"Qobuz Track Id", m.QobuzTrackID},
+		{"MusicBrainz Track Id", m.MusicBrainzTrackID},
+		{"MusicBrainz Album Id", m.MusicBrainzAlbumID},
+		{"MusicBrainz Artist Id", m.MusicBrainzArtistID},
+		{"MusicBrainz Album Artist Id", m.MusicBrainzAlbumArtistID},
+		{"MusicBrainz Release Group Id", m.MusicBrainzReleaseGroupID},
+	}
+
+	if rg := m.ReplayGain; rg != nil {
+		fields = append(fields,
+			tagField{"replaygain_track_gain", fmt.Sprintf("%.2f dB", rg.TrackGain)},
+			tagField{"replaygain_track_peak", fmt.Sprintf("%.6f", rg.TrackPeak)},
+		)
+		if rg.HasAlbum {
+			fields = append(fields,
+				tagField{"replaygain_album_gain", fmt.Sprintf("%.2f dB", rg.AlbumGain)},
+				tagField{"replaygain_album_peak", fmt.Sprintf("%.6f", rg.AlbumPeak)},
+			)
+		}
+	}
+
+	return fields
+}
+
+func buildMP4Items(existing []*mp4Atom, metadata Metadata, cover []byte) []*mp4Atom {
+	var items []*mp4Atom
+	for _, item := range existing {
+		switch item.typ {
+		case "trkn", "disk", "tmpo":
+			continue
+		case "gnre":
+			if metadata.Genre != "" {
+				continue
+			}
+		case "covr":
+			if cover != nil {
+				continue
+			}
+		case "----":
+			if mean, name := mp4FreeformName(item); mean == mp4FreeformMean && isCanonicalTagKey(name) {
+				continue
+			}
+		default:
+			if _, ok := mp4TextAtoms[item.typ]; ok {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+
+	text := map[string]string{
+		"\xa9nam": metadata.Title,
+		"\xa9ART": metadata.Artist,
+		"aART":    metadata.AlbumArtist,
+		"\xa9alb": metadata.Album,
+		"\xa9day": metadata.Date,
+		"\xa9gen": metadata.Genre,
+		"\xa9wrt": metadata.Composer,
+		"\xa9lyr": metadata.Lyrics,
+		"\xa9cmt": metadata.Description,
+		"cprt":    metadata.Copyright,
+	}
+	for _, typ := range []string{"\xa9nam", "\xa9ART", "aART", "\xa9alb", "\xa9day", "\xa9gen", "\xa9wrt", "\xa9lyr", "\xa9cmt", "cprt"} {
+		if value := text[typ]; value != "" {
+			items = append(items, newMP4DataItem(typ, mp4DataUTF8, []byte(value)))
+		}
+	}
+
+	if metadata.TrackNumber > 0 {
+		items = append(items, newMP4DataItem("trkn", mp4DataImplicit, mp4NumberPair(metadata.TrackNumber, metadata.TotalTracks, true)))
+	}
+	if metadata.DiscNumber > 0 {
+		items = append(items, newMP4DataItem("disk", mp4DataImplicit, mp4NumberPair(metadata.DiscNumber, metadata.TotalDiscs, false)))
+	}
+	if metadata.BPM > 0 {
+		items = append(items, newMP4DataItem("tmpo", mp4DataInteger, binary.BigEndian.AppendUint16(nil, uint16(metadata.BPM))))
+	}
+
+	for _, field := range mp4FreeformFields(metadata) {
+		if field.value != "" {
+			items = append(items, newMP4FreeformItem(field.key, field.value))
+		}
+	}
+
+	if len(cover) > 0 {
+		items = append(items, newMP4DataItem("covr", mp4CoverType(cover), cover))
+	}
+
+	return items
+}
+
+func mp4MetaAtom(moov *mp4Atom) *mp4Atom {
+	udta := moov.child("udta")
+	if udta == nil {
+		udta = &mp4Atom{typ: "udta", container: true}
+		moov.children = append(moov.children, udta)
+	}
+
+	meta := udta.child("meta")
+	if meta == nil {
+		meta = &mp4Atom{typ: "meta", container: true, prefix: []byte{0, 0, 0, 0}}
+		udta.children = append(udta.children, meta)
+	}
+
+	if meta.child("hdlr") == nil {
+		hdlr := &mp4Atom{typ: "hdlr", data: append(make([]byte, 8), "mdirappl\x00\x00\x00\x00\x00\x00\x00\x00\x00"...)}
+		meta.children = append([]*mp4Atom{hdlr}, meta.children...)
+	}
+
+	if meta.child("ilst") == nil {
+		meta.children = append(meta.children, &mp4Atom{typ: "ilst", container: true})
+	}
+
+	return meta
+}
+
+func resizeMP4Padding(meta *mp4Atom, delta int64) bool {
+	for i, child := range meta.children {
+		if child.typ != "free" {
+			continue
+		}
+		newSize := 8 + int64(len(child.data)) - delta
+		switch {
+		case newSize == 0:
+			meta.children = append(meta.children[:i], meta.children[i+1:]...)
+			return true
+		case newSize >= 8:
+			child.data = make([]byte, newSize-8)
+			return true
+		}
+		return false
+	}
+
+	if delta <= -8 {
+		meta.children = append(meta.children, &mp4Atom{typ: "free", data: make([]byte, -delta-8)})
+		return true
+	}
+	return false
+}
+
+func shiftMP4ChunkOffsets(moov *mp4Atom, after, delta int64) error {
+	for _, trak := range moov.children {
+		if trak.typ != "trak" {
+			continue
+		}
+		stbl := trak.path("mdia", "minf", "stbl")
+		if stbl == nil {
+			continue
+		}
+
+		for _, table := range stbl.children {
+			if table.typ != "stco" && table.typ != "co64" || len(table.data) < 8 {
+				continue
+			}
+
+			count := int(binary.BigEndian.Uint32(table.data[4:]))
+			entrySize := 4
+			if table.typ == "co64" {
+				entrySize = 8
+			}
+			if len(table.data) < 8+count*entrySize {
+				return fmt.Errorf("truncated %s atom", table.typ)
+			}
+
+			for i := 0; i < count; i++ {
+				pos := table.data[8+i*entrySize:]
+				if entrySize == 8 {
+					if off := int64(binary.BigEndian.Uint64(pos)); off > after {
+						binary.BigEndian.PutUint64(pos, uint64(off+delta))
+					}
+					continue
+				}
+				if off := int64(binary.BigEndian.Uint32(pos)); off > after {
+					if off+delta > 0xffffffff {
+						return fmt.Errorf("chunk offset overflow")
+					}
+					binary.BigEndian.PutUint32(pos, uint32(off+delta))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func writeMP4Tags(filePath string, metadata Metadata, cover []byte) error {
+	mf, err := openMP4File(filePath, os.O_RDWR)
+	if err != nil {
+		return err
+	}
+	defer mf.file.Close()
+
+	meta := mp4MetaAtom(mf.moov)
+	ilst := meta.child("ilst")
+	ilst.children = buildMP4Items(ilst.children, metadata, cover)
+
+	old := mf.atoms[mf.index]
+	delta := mf.moov.size() - old.size
+	if delta != 0 && resizeMP4Padding(meta, delta) {
+		delta = 0
+	}
+
+	var next *mp4TopAtom
+	if mf.index+1 < len(mf.atoms) {
+		next = &mf.atoms[mf.index+1]
+	}
+
+	if delta == 0 || next != nil && (next.typ == "free" || next.typ == "skip") && (delta == next.size || delta <= next.size-8) {
+		buf, err := mf.moov.appendTo(nil)
+		if err != nil {
+			return err
+		}
+		if delta != 0 && delta != next.size {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(next.size-delta))
+			buf = append(buf, "free"...)
+		}
+		if _, err := mf.file.WriteAt(buf, old.offset); err != nil {
+			return fmt.Errorf("failed to write moov atom: %w", err)
+		}
+		return nil
+	}
+
+	return rewriteMP4File(mf, meta)
+}
+
+func rewriteMP4File(mf *mp4File, meta *mp4Atom) error {
+	old := mf.atoms[mf.index]
+	for _, atom := range mf.atoms {
+		if atom.typ == "moof" {
+			return fmt.Errorf("fragmented MP4 files are not supported")
+		}
+	}
+
+	resizeMP4Padding(meta, -mp4PaddingSize)
+	delta := mf.moov.size() - old.size
+	if err := shiftMP4ChunkOffsets(mf.moov, old.offset, delta); err != nil {
+		return err
+	}
+
+	buf, err := mf.moov.appendTo(nil)
+	if err != nil {
+		return err
+	}
+
+	filePath := mf.file.Name()
+	tmpPath := strings.TrimSuffix(filePath, pathfilepath.Ext(filePath)) + ".tmp" + pathfilepath.Ext(filePath)
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	info, err := mf.file.Stat()
+	if err != nil {
+		out.Close()
+		return err
+	}
+
+	_, err = io.Copy(out, io.NewSectionReader(mf.file, 0, old.offset))
+	if err == nil {
+		_, err = out.Write(buf)
+	}
+	if err == nil {
+		end := old.offset + old.size
+		_, err = io.Copy(out, io.NewSectionReader(mf.file, end, info.Size()-end))
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write MP4 file: %w", err)
+	}
+
+	mf.file.Close()
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to replace original file: %w", err)
+	}
+
+	return nil
+}
+
+func readMP4Cover(filePath string) ([]byte, error) {
+	_, cover, err := readMP4Tags(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if cover == nil {
+		return nil, fmt.Errorf("no cover art found")
+	}
+	return cover, nil
+}
+
+func getMP4Duration(filePath string) (float64, error) {
+	mf, err := openMP4File(filePath, os.O_RDONLY)
+	if err != nil {
+		return 0, err
+	}
+	defer mf.file.Close()
+
+	mvhd := mf.moov.child("mvhd")
+	if mvhd == nil || len(mvhd.data) < 20 {
+		return 0, fmt.Errorf("no mvhd atom found")
+	}
+
+	var timescale uint32
+	var duration uint64
+	if mvhd.data[0] == 1 {
+		if len(mvhd.data) < 32 {
+			return 0, fmt.Errorf("truncated mvhd atom")
+		}
+		timescale = binary.BigEndian.Uint32(mvhd.data[20:])
+		duration = binary.BigEndian.Uint64(mvhd.data[24:])
+	} else {
+		timescale = binary.BigEndian.Uint32(mvhd.data[12:])
+		duration = uint64(binary.BigEndian.Uint32(mvhd.data[16:]))
+	}
+
+	if timescale == 0 {
+		return 0, fmt.Errorf("invalid mvhd timescale")
+	}
+	return float64(duration) / float64(timescale), nil
+}
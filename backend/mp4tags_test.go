@@ -0,0 +1,131 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var mp4TestSample = []byte("sample-bytes-that-must-not-move")
+
+func mp4TestAtom(typ string, parts ...[]byte) []byte {
+	body := bytes.Join(parts, nil)
+	out := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(out, typ...), body...)
+}
+
+// writeMP4Fixture builds ftyp, moov and mdat with a single stco entry pointing
+// at mp4TestSample. udta ends with the 4-byte zero terminator QuickTime writes.
+func writeMP4Fixture(t *testing.T, padding int) string {
+	t.Helper()
+
+	ftyp := mp4TestAtom("ftyp", []byte("M4A \x00\x00\x00\x00M4A "))
+
+	title := mp4TestAtom("\xa9nam", mp4TestAtom("data", []byte{0, 0, 0, mp4DataUTF8, 0, 0, 0, 0}, []byte("Old Title")))
+	hdlr := mp4TestAtom("hdlr", make([]byte, 8), []byte("mdirappl\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+	metaParts := [][]byte{{0, 0, 0, 0}, hdlr, mp4TestAtom("ilst", title)}
+	if padding > 0 {
+		metaParts = append(metaParts, mp4TestAtom("free", make([]byte, padding)))
+	}
+	udta := mp4TestAtom("udta", mp4TestAtom("meta", metaParts...), []byte{0, 0, 0, 0})
+
+	buildMoov := func(chunkOffset uint32) []byte {
+		stco := mp4TestAtom("stco", []byte{0, 0, 0, 0, 0, 0, 0, 1}, binary.BigEndian.AppendUint32(nil, chunkOffset))
+		trak := mp4TestAtom("trak", mp4TestAtom("mdia", mp4TestAtom("minf", mp4TestAtom("stbl", stco))))
+		return mp4TestAtom("moov", trak, udta)
+	}
+
+	moovSize := len(buildMoov(0))
+	offset := uint32(len(ftyp) + moovSize + 8)
+	file := bytes.Join([][]byte{ftyp, buildMoov(offset), mp4TestAtom("mdat", mp4TestSample)}, nil)
+
+	path := filepath.Join(t.TempDir(), "fixture.m4a")
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func assertMP4SampleIntact(t *testing.T, path string) {
+	t.Helper()
+
+	mf, err := openMP4File(path, os.O_RDONLY)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer mf.file.Close()
+
+	stco := mf.moov.path("trak", "mdia", "minf", "stbl", "stco")
+	if stco == nil || len(stco.data) < 12 {
+		t.Fatal("stco atom missing after write")
+	}
+	offset := int64(binary.BigEndian.Uint32(stco.data[8:]))
+
+	got := make([]byte, len(mp4TestSample))
+	if _, err := mf.file.ReadAt(got, offset); err != nil {
+		t.Fatalf("read sample at %d: %v", offset, err)
+	}
+	if !bytes.Equal(got, mp4TestSample) {
+		t.Errorf("chunk offset %d points at %q, want %q", offset, got, mp4TestSample)
+	}
+
+	udta := mf.moov.child("udta")
+	if udta == nil || !bytes.Equal(udta.trailer, []byte{0, 0, 0, 0}) {
+		t.Errorf("udta terminator not preserved")
+	}
+}
+
+func TestMP4TagsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		padding int
+	}{
+		{"padding absorbs change", 1024},
+		{"file rewritten", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeMP4Fixture(t, tt.padding)
+			before, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata, _, err := readMP4Tags(path)
+			if err != nil {
+				t.Fatalf("readMP4Tags: %v", err)
+			}
+			if metadata.Title != "Old Title" {
+				t.Fatalf("title = %q, want %q", metadata.Title, "Old Title")
+			}
+
+			metadata.Title = "New Title"
+			metadata.Artist = "Some Artist"
+			metadata.Lyrics = "la la la"
+			if err := writeMP4Tags(path, metadata, nil); err != nil {
+				t.Fatalf("writeMP4Tags: %v", err)
+			}
+
+			after, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if grew := after.Size() != before.Size(); grew == (tt.padding > 0) {
+				t.Errorf("file size %d -> %d, padding %d", before.Size(), after.Size(), tt.padding)
+			}
+
+			got, _, err := readMP4Tags(path)
+			if err != nil {
+				t.Fatalf("readMP4Tags after write: %v", err)
+			}
+			if got.Title != "New Title" || got.Artist != "Some Artist" || got.Lyrics != "la la la" {
+				t.Errorf("tags after write = %+v", got)
+			}
+
+			assertMP4SampleIntact(t, path)
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package backend
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	PlaylistFormatM3U8 = "m3u8"
+	PlaylistFormatXSPF = "xspf"
+)
+
+type PlaylistExportRequest struct {
+	Name      string               `json:"name"`
+	OutputDir string               `json:"output_dir"`
+	Tracks    []AlbumTrackMetadata `json:"tracks"`
+	Files     []string             `json:"files"`
+	Formats   []string             `json:"formats,omitempty"`
+}
+
+type PlaylistExportResult struct {
+	M3U8Path string `json:"m3u8_path,omitempty"`
+	XSPFPath string `json:"xspf_path,omitempty"`
+	Written  int    `json:"written"`
+	Missing  int    `json:"missing"`
+}
+
+type playlistEntry struct {
+	track      AlbumTrackMetadata
+	path       string
+	relPath    string
+	durationMS int
+}
+
+func playlistEntries(req PlaylistExportRequest, baseDir string) ([]playlistEntry, int) {
+	entries := make([]playlistEntry, 0, len(req.Tracks))
+	missing := 0
+
+	for i, track := range req.Tracks {
+		entry := playlistEntry{track: track}
+		if i < len(req.Files) && req.Files[i] != "" {
+			if _, err := os.Stat(req.Files[i]); err == nil {
+				entry.path = req.Files[i]
+			}
+		}
+
+		if entry.path == "" {
+			missing++
+			entries = append(entries, entry)
+			continue
+		}
+
+		entry.relPath = entry.path
+		if rel, err := filepath.Rel(libraryPathKey(baseDir), libraryPathKey(entry.path)); err == nil {
+			entry.relPath = rel
+		}
+		entry.relPath = filepath.ToSlash(entry.relPath)
+
+		entry.durationMS = track.DurationMS
+		if entry.durationMS <= 0 {
+			if d, err := GetAudioDuration(entry.path); err == nil {
+				entry.durationMS = int(d * 1000)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, missing
+}
+
+func playlistDisplayName(track AlbumTrackMetadata) string {
+	name := track.Name
+	if track.Artists != "" {
+		name = fmt.Sprintf("%s - %s", track.Artists, track.Name)
+	}
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(name)
+}
+
+func playlistMissingComment(track AlbumTrackMetadata) string {
+	comment := "Missing: " + playlistDisplayName(track)
+	if track.SpotifyID != "" {
+		comment += fmt.Sprintf(" (https://open.spotify.com/track/%s)", track.SpotifyID)
+	}
+	return comment
+}
+
+func buildM3U8(name string, entries []playlistEntry) string {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n")
+	if name != "" {
+		sb.WriteString(fmt.Sprintf("#PLAYLIST:%s\n", name))
+	}
+
+	for _, entry := range entries {
+		if entry.path == "" {
+			sb.WriteString(fmt.Sprintf("# %s\n", playlistMissingComment(entry.track)))
+			continue
+		}
+		seconds := -1
+		if entry.durationMS > 0 {
+			seconds = (entry.durationMS + 500) / 1000
+		}
+		sb.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", seconds, playlistDisplayName(entry.track)))
+		sb.WriteString(entry.relPath + "\n")
+	}
+
+	return sb.String()
+}
+
+func xspfLocation(relPath string) string {
+	segments := strings.Split(relPath, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func buildXSPF(name string, entries []playlistEntry) string {
+	var sb strings.Builder
+	sb.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	sb.WriteString("<playlist version=\"1\" xmlns=\"http://xspf.org/ns/0/\">\n")
+	if name != "" {
+		sb.WriteString(fmt.Sprintf("  <title>%s</title>\n", escapeXML(name)))
+	}
+	sb.WriteString("  <trackList>\n")
+
+	for _, entry := range entries {
+		if entry.path == "" {
+			comment := strings.ReplaceAll(playlistMissingComment(entry.track), "--", "- -")
+			sb.WriteString(fmt.Sprintf("    <!-- %s -->\n", comment))
+			continue
+		}
+
+		sb.WriteString("    <track>\n")
+		sb.WriteString(fmt.Sprintf("      <location>%s</location>\n", escapeXML(xspfLocation(entry.relPath))))
+		if entry.track.SpotifyID != "" {
+			sb.WriteString(fmt.Sprintf("      <identifier>https://open.spotify.com/track/%s</identifier>\n", escapeXML(entry.track.SpotifyID)))
+		}
+		sb.WriteString(fmt.Sprintf("      <title>%s</title>\n", escapeXML(entry.track.Name)))
+		if entry.track.Artists != "" {
+			sb.WriteString(fmt.Sprintf("      <creator>%s</creator>\n", escapeXML(entry.track.Artists)))
+		}
+		if entry.track.AlbumName != "" {
+			sb.WriteString(fmt.Sprintf("      <album>%s</album>\n", escapeXML(entry.track.AlbumName)))
+		}
+		if entry.durationMS > 0 {
+			sb.WriteString(fmt.Sprintf("      <duration>%d</duration>\n", entry.durationMS))
+		}
+		sb.WriteString("    </track>\n")
+	}
+
+	sb.WriteString("  </trackList>\n</playlist>\n")
+	return sb.String()
+}
+
+func ExportPlaylist(req PlaylistExportRequest) (*PlaylistExportResult, error) {
+	if req.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if len(req.Tracks) == 0 {
+		return nil, fmt.Errorf("no tracks to export")
+	}
+
+	outputDir := NormalizePath(req.OutputDir)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	formats := req.Formats
+	if len(formats) == 0 {
+		formats = []string{PlaylistFormatM3U8}
+	}
+
+	baseName := "playlist"
+	if req.Name != "" {
+		baseName = sanitizeFilename(req.Name)
+	}
+
+	entries, missing := playlistEntries(req, outputDir)
+	result := &PlaylistExportResult{
+		Written: len(entries) - missing,
+		Missing: missing,
+	}
+
+	for _, format := range formats {
+		switch strings.ToLower(format) {
+		case PlaylistFormatM3U8:
+			result.M3U8Path = filepath.Join(outputDir, baseName+".m3u8")
+			if err := os.WriteFile(result.M3U8Path, []byte(buildM3U8(req.Name, entries)), 0644); err != nil {
+				return result, fmt.Errorf("failed to write M3U8 playlist: %w", err)
+			}
+		case PlaylistFormatXSPF:
+			result.XSPFPath = filepath.Join(outputDir, baseName+".xspf")
+			if err := os.WriteFile(result.XSPFPath, []byte(buildXSPF(req.Name, entries)), 0644); err != nil {
+				return result, fmt.Errorf("failed to write XSPF playlist: %w", err)
+			}
+		default:
+			return result, fmt.Errorf("unsupported playlist format: %s", format)
+		}
+	}
+
+	fmt.Printf("Playlist exported: %d tracks, %d missing\n", result.Written, result.Missing)
+	return result, nil
+}
@@ -15,6 +15,7 @@ const (
 	StatusCompleted   DownloadStatus = "completed"
 	StatusFailed      DownloadStatus = "failed"
 	StatusSkipped     DownloadStatus = "skipped"
+	StatusSuspect     DownloadStatus = "suspect"
 )
 
 type DownloadItem struct {
@@ -34,17 +35,8 @@ type DownloadItem struct {
 }
 
 var (
-	currentProgress     float64
-	currentProgressLock sync.RWMutex
-	isDownloading       bool
-	downloadingLock     sync.RWMutex
-	currentSpeed        float64
-	speedLock           sync.RWMutex
-
 	downloadQueue       []DownloadItem
 	downloadQueueLock   sync.RWMutex
-	currentItemID       string
-	currentItemLock     sync.RWMutex
 	totalDownloaded     float64
 	totalDownloadedLock sync.RWMutex
 	sessionStartTime    int64
@@ -67,20 +59,22 @@ type DownloadQueueInfo struct {
 	CompletedCount   int            `json:"completed_count"`
 	FailedCount      int            `json:"failed_count"`
 	SkippedCount     int            `json:"skipped_count"`
+	SuspectCount     int            `json:"suspect_count"`
 }
 
 func GetDownloadProgress() ProgressInfo {
-	downloadingLock.RLock()
-	downloading := isDownloading
-	downloadingLock.RUnlock()
-
-	currentProgressLock.RLock()
-	progress := currentProgress
-	currentProgressLock.RUnlock()
+	var downloading bool
+	var progress, speed float64
 
-	speedLock.RLock()
-	speed := currentSpeed
-	speedLock.RUnlock()
+	downloadQueueLock.RLock()
+	for _, item := range downloadQueue {
+		if item.Status == StatusDownloading {
+			downloading = true
+			progress += item.Progress
+			speed += item.Speed
+		}
+	}
+	downloadQueueLock.RUnlock()
 
 	return ProgressInfo{
 		IsDownloading: downloading,
@@ -89,30 +83,6 @@ func GetDownloadProgress() ProgressInfo {
 	}
 }
 
-func SetDownloadSpeed(mbps float64) {
-	speedLock.Lock()
-	currentSpeed = mbps
-	speedLock.Unlock()
-}
-
-func SetDownloadProgress(mbDownloaded float64) {
-	currentProgressLock.Lock()
-	currentProgress = mbDownloaded
-	currentProgressLock.Unlock()
-}
-
-func SetDownloading(downloading bool) {
-	downloadingLock.Lock()
-	isDownloading = downloading
-	downloadingLock.Unlock()
-
-	if !downloading {
-
-		SetDownloadProgress(0)
-		SetDownloadSpeed(0)
-	}
-}
-
 type ProgressWriter struct {
 	writer      io.Writer
 	total       int64
@@ -160,17 +130,12 @@ func (pw *ProgressWriter) Write(p []byte) (int, error) {
 		var speedMBps float64
 		if timeDiff > 0 {
 			speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
-			SetDownloadSpeed(speedMBps)
 			fmt.Printf("\rDownloaded: %.2f MB (%.2f MB/s)", mbDownloaded, speedMBps)
 		} else {
 			fmt.Printf("\rDownloaded: %.2f MB", mbDownloaded)
 		}
 
-		SetDownloadProgress(mbDownloaded)
-
-		if pw.itemID != "" {
-			UpdateItemProgress(pw.itemID, mbDownloaded, speedMBps)
-		}
+		UpdateItemProgress(pw.itemID, mbDownloaded, speedMBps)
 
 		pw.lastPrinted = pw.total
 		pw.lastTime = now
@@ -186,114 +151,179 @@ func (pw *ProgressWriter) GetTotal() int64 {
 
 func AddToQueue(id, trackName, artistName, albumName, isrc string) {
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
 
-	item := DownloadItem{
-		ID:         id,
-		TrackName:  trackName,
-		ArtistName: artistName,
-		AlbumName:  albumName,
-		ISRC:       isrc,
-		Status:     StatusQueued,
-		Progress:   0,
-		TotalSize:  0,
-		Speed:      0,
-		StartTime:  0,
-		EndTime:    0,
+	requeued := false
+	for i := range downloadQueue {
+		if downloadQueue[i].ID == id {
+			downloadQueue[i].Status = StatusQueued
+			downloadQueue[i].Progress = 0
+			downloadQueue[i].Speed = 0
+			downloadQueue[i].EndTime = 0
+			downloadQueue[i].ErrorMessage = ""
+			requeued = true
+			break
+		}
 	}
 
-	downloadQueue = append(downloadQueue, item)
+	if !requeued {
+		item := DownloadItem{
+			ID:         id,
+			TrackName:  trackName,
+			ArtistName: artistName,
+			AlbumName:  albumName,
+			ISRC:       isrc,
+			Status:     StatusQueued,
+			Progress:   0,
+			TotalSize:  0,
+			Speed:      0,
+			StartTime:  0,
+			EndTime:    0,
+		}
+
+		downloadQueue = append(downloadQueue, item)
+	}
+	downloadQueueLock.Unlock()
 
 	sessionStartLock.Lock()
 	if sessionStartTime == 0 {
 		sessionStartTime = time.Now().Unix()
 	}
 	sessionStartLock.Unlock()
+
+	emitQueueChanged()
 }
 
-func StartDownloadItem(id string) {
+func updateDownloadItem(id string, update func(item *DownloadItem)) (DownloadItem, bool) {
 	downloadQueueLock.Lock()
 	defer downloadQueueLock.Unlock()
 
 	for i := range downloadQueue {
 		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusDownloading
-			downloadQueue[i].StartTime = time.Now().Unix()
-			downloadQueue[i].Progress = 0
-			break
+			update(&downloadQueue[i])
+			return downloadQueue[i], true
 		}
 	}
+	return DownloadItem{}, false
+}
+
+func StartDownloadItem(id string) {
+	persistDownloadStatus(id, StatusDownloading, "")
+
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Status = StatusDownloading
+		item.StartTime = time.Now().Unix()
+		item.Progress = 0
+		item.Speed = 0
+	})
+	if !ok {
+		return
+	}
 
-	currentItemLock.Lock()
-	currentItemID = id
-	currentItemLock.Unlock()
+	emitDownloadEvent(EventItemStarted, item)
+	emitQueueChanged()
 }
 
 func UpdateItemProgress(id string, progress, speed float64) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Progress = progress
-			downloadQueue[i].Speed = speed
-			break
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Progress = progress
+		if speed > 0 {
+			item.Speed = speed
 		}
+	})
+	if !ok {
+		return
 	}
+
+	emitItemProgress(id, item.Progress, item.Speed)
 }
 
-func GetCurrentItemID() string {
-	currentItemLock.RLock()
-	defer currentItemLock.RUnlock()
-	return currentItemID
+func GetDownloadItem(id string) (DownloadItem, bool) {
+	downloadQueueLock.RLock()
+	defer downloadQueueLock.RUnlock()
+
+	for _, item := range downloadQueue {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return DownloadItem{}, false
 }
 
 func CompleteDownloadItem(id, filePath string, finalSize float64) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	DeletePersistedDownloads([]string{id})
+
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Status = StatusCompleted
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Progress = finalSize
+		item.TotalSize = finalSize
+	})
+	if !ok {
+		return
+	}
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusCompleted
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			downloadQueue[i].Progress = finalSize
-			downloadQueue[i].TotalSize = finalSize
+	totalDownloadedLock.Lock()
+	totalDownloaded += finalSize
+	totalDownloadedLock.Unlock()
 
-			totalDownloadedLock.Lock()
-			totalDownloaded += finalSize
-			totalDownloadedLock.Unlock()
-			break
-		}
-	}
+	emitItemFinished(item)
+	emitQueueChanged()
 }
 
 func FailDownloadItem(id, errorMsg string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
-
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusFailed
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].ErrorMessage = errorMsg
-			break
-		}
+	persistDownloadStatus(id, StatusFailed, errorMsg)
+
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Status = StatusFailed
+		item.EndTime = time.Now().Unix()
+		item.ErrorMessage = errorMsg
+	})
+	if !ok {
+		return
 	}
+
+	emitItemFinished(item)
+	emitQueueChanged()
 }
 
 func SkipDownloadItem(id, filePath string) {
-	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
+	DeletePersistedDownloads([]string{id})
+
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Status = StatusSkipped
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+	})
+	if !ok {
+		return
+	}
 
-	for i := range downloadQueue {
-		if downloadQueue[i].ID == id {
-			downloadQueue[i].Status = StatusSkipped
-			downloadQueue[i].EndTime = time.Now().Unix()
-			downloadQueue[i].FilePath = filePath
-			break
-		}
+	emitItemFinished(item)
+	emitQueueChanged()
+}
+
+func SuspectDownloadItem(id, filePath string, finalSize float64, reason string) {
+	DeletePersistedDownloads([]string{id})
+
+	item, ok := updateDownloadItem(id, func(item *DownloadItem) {
+		item.Status = StatusSuspect
+		item.EndTime = time.Now().Unix()
+		item.FilePath = filePath
+		item.Progress = finalSize
+		item.TotalSize = finalSize
+		item.ErrorMessage = reason
+	})
+	if !ok {
+		return
 	}
+
+	totalDownloadedLock.Lock()
+	totalDownloaded += finalSize
+	totalDownloadedLock.Unlock()
+
+	emitItemFinished(item)
+	emitQueueChanged()
 }
 
 func GetDownloadQueue() DownloadQueueInfo {
@@ -303,13 +333,7 @@ func GetDownloadQueue() DownloadQueueInfo {
 	downloadQueueLock.RLock()
 	defer downloadQueueLock.RUnlock()
 
-	downloadingLock.RLock()
-	downloading := isDownloading
-	downloadingLock.RUnlock()
-
-	speedLock.RLock()
-	speed := currentSpeed
-	speedLock.RUnlock()
+	downloading := false
 
 	totalDownloadedLock.RLock()
 	total := totalDownloaded
@@ -319,9 +343,13 @@ func GetDownloadQueue() DownloadQueueInfo {
 	sessionStart := sessionStartTime
 	sessionStartLock.RUnlock()
 
-	var queued, completed, failed, skipped int
+	var speed float64
+	var queued, completed, failed, skipped, suspect int
 	for _, item := range downloadQueue {
 		switch item.Status {
+		case StatusDownloading:
+			downloading = true
+			speed += item.Speed
 		case StatusQueued:
 			queued++
 		case StatusCompleted:
@@ -330,6 +358,8 @@ func GetDownloadQueue() DownloadQueueInfo {
 			failed++
 		case StatusSkipped:
 			skipped++
+		case StatusSuspect:
+			suspect++
 		}
 	}
 
@@ -346,20 +376,27 @@ func GetDownloadQueue() DownloadQueueInfo {
 		CompletedCount:   completed,
 		FailedCount:      failed,
 		SkippedCount:     skipped,
+		SuspectCount:     suspect,
 	}
 }
 
 func ClearDownloadQueue() {
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
 
 	newQueue := make([]DownloadItem, 0)
+	var cleared []string
 	for _, item := range downloadQueue {
 		if item.Status == StatusQueued || item.Status == StatusDownloading {
 			newQueue = append(newQueue, item)
+		} else {
+			cleared = append(cleared, item.ID)
 		}
 	}
 	downloadQueue = newQueue
+	downloadQueueLock.Unlock()
+
+	DeletePersistedDownloads(cleared)
+	emitQueueChanged()
 }
 
 func ClearAllDownloads() {
@@ -375,25 +412,26 @@ func ClearAllDownloads() {
 	sessionStartTime = 0
 	sessionStartLock.Unlock()
 
-	currentItemLock.Lock()
-	currentItemID = ""
-	currentItemLock.Unlock()
-
-	SetDownloadProgress(0)
-	SetDownloadSpeed(0)
+	ClearPersistedDownloads()
+	emitQueueChanged()
 }
 
 func CancelAllQueuedItems() {
 	downloadQueueLock.Lock()
-	defer downloadQueueLock.Unlock()
 
+	var cancelled []string
 	for i := range downloadQueue {
 		if downloadQueue[i].Status == StatusQueued {
 			downloadQueue[i].Status = StatusSkipped
 			downloadQueue[i].EndTime = time.Now().Unix()
 			downloadQueue[i].ErrorMessage = "Cancelled"
+			cancelled = append(cancelled, downloadQueue[i].ID)
 		}
 	}
+	downloadQueueLock.Unlock()
+
+	DeletePersistedDownloads(cancelled)
+	emitQueueChanged()
 }
 
 func ResetSessionIfComplete() {
@@ -0,0 +1,95 @@
+package backend
+
+import (
+	"fmt"
+	"sync"
+)
+
+type ProviderCapabilities struct {
+	ResolveBySpotifyID bool `json:"resolve_by_spotify_id"`
+	ResolveByISRC      bool `json:"resolve_by_isrc"`
+	ResolveByURL       bool `json:"resolve_by_url"`
+	HiRes              bool `json:"hi_res"`
+}
+
+type ProviderRequest struct {
+	ServiceURL      string
+	ApiURL          string
+	AcquireProvider func(service string) (release func())
+	TrackRequest
+}
+
+type Provider interface {
+	Name() string
+	Capabilities() ProviderCapabilities
+	Qualities() []string
+	ResolveTrack(req ProviderRequest) (string, error)
+	ProbeQuality(trackRef string) (string, error)
+
+	// FetchTrack returns the path of the finished file rather than a stream:
+	// providers assemble DASH segments, remux and embed tags and cover art in
+	// place, so only a completed file on disk is meaningful to the caller.
+	FetchTrack(trackRef string, req ProviderRequest) (string, error)
+}
+
+type ProviderFactory func() Provider
+
+type ProviderInfo struct {
+	Name         string               `json:"name"`
+	Qualities    []string             `json:"qualities"`
+	Capabilities ProviderCapabilities `json:"capabilities"`
+}
+
+var (
+	providerFactories = make(map[string]ProviderFactory)
+	providerOrder     []string
+	providersLock     sync.RWMutex
+)
+
+func init() {
+	RegisterProvider("tidal", func() Provider { return NewTidalDownloader("") })
+	RegisterProvider("qobuz", func() Provider { return NewQobuzDownloader() })
+	RegisterProvider("amazon", func() Provider { return NewAmazonDownloader() })
+}
+
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersLock.Lock()
+	defer providersLock.Unlock()
+
+	if _, exists := providerFactories[name]; !exists {
+		providerOrder = append(providerOrder, name)
+	}
+	providerFactories[name] = factory
+}
+
+func GetProvider(name string) (Provider, error) {
+	providersLock.RLock()
+	factory, ok := providerFactories[name]
+	providersLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown service: %s", name)
+	}
+	return factory(), nil
+}
+
+func ListProviders() []ProviderInfo {
+	providersLock.RLock()
+	names := make([]string, len(providerOrder))
+	copy(names, providerOrder)
+	providersLock.RUnlock()
+
+	infos := make([]ProviderInfo, 0, len(names))
+	for _, name := range names {
+		provider, err := GetProvider(name)
+		if err != nil {
+			continue
+		}
+		infos = append(infos, ProviderInfo{
+			Name:         provider.Name(),
+			Qualities:    provider.Qualities(),
+			Capabilities: provider.Capabilities(),
+		})
+	}
+	return infos
+}
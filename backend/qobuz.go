@@ -9,10 +9,17 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
 
+var isrcRegex = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{3}\d{2}\d{5}$`)
+
+func isValidISRC(isrc string) bool {
+	return isrcRegex.MatchString(isrc)
+}
+
 type QobuzDownloader struct {
 	client *http.Client
 	appID  string
@@ -219,39 +226,21 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string) (string,
 	return streamResp.URL, nil
 }
 
-func (q *QobuzDownloader) DownloadFile(url, filepath string) error {
+func (q *QobuzDownloader) DownloadFile(url, filepath, itemID string) error {
 	fmt.Println("Starting file download...")
 
 	downloadClient := &http.Client{
 		Timeout: 5 * time.Minute,
 	}
 
-	resp, err := downloadClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
-	fmt.Printf("Creating file: %s\n", filepath)
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
-	fmt.Println("Downloading...")
+	fmt.Printf("Downloading to: %s\n", filepath)
 
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, resp.Body)
+	total, err := DownloadResumable(downloadClient, url, filepath, itemID)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return err
 	}
 
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
+	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(total)/(1024*1024))
 	return nil
 }
 
@@ -280,11 +269,20 @@ func (q *QobuzDownloader) DownloadCoverArt(coverURL, filepath string) error {
 	return err
 }
 
-func buildQobuzFilename(title, artist, album, albumArtist, releaseDate string, trackNumber, discNumber int, format string, includeTrackNumber bool, position int, useAlbumTrackNumber bool) string {
+func buildQobuzFilename(req TrackRequest, trackNumber int) string {
 	var filename string
 
+	title := sanitizeFilename(req.Tags.Title)
+	artist := sanitizeFilename(req.Tags.Artist)
+	album := sanitizeFilename(req.Tags.Album)
+	albumArtist := sanitizeFilename(req.Tags.AlbumArtist)
+	releaseDate := req.Tags.ReleaseDate
+	discNumber := req.Tags.DiscNumber
+	format := req.FilenameFormat
+	position := req.Position
+
 	numberToUse := position
-	if useAlbumTrackNumber && trackNumber > 0 {
+	if req.UseAlbumTrackNumber && trackNumber > 0 {
 		numberToUse = trackNumber
 	}
 
@@ -326,7 +324,7 @@ func buildQobuzFilename(title, artist, album, albumArtist, releaseDate string, t
 			filename = fmt.Sprintf("%s - %s", title, artist)
 		}
 
-		if includeTrackNumber && position > 0 {
+		if req.IncludeTrackNumber && position > 0 {
 			filename = fmt.Sprintf("%02d. %s", numberToUse, filename)
 		}
 	}
@@ -334,11 +332,11 @@ func buildQobuzFilename(title, artist, album, albumArtist, releaseDate string, t
 	return filename + ".flac"
 }
 
-func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
+func (q *QobuzDownloader) DownloadByISRC(deezerISRC string, req TrackRequest) (string, error) {
 	fmt.Printf("Fetching track info for ISRC: %s\n", deezerISRC)
 
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
 			return "", fmt.Errorf("failed to create output directory: %w", err)
 		}
 	}
@@ -348,12 +346,8 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 		return "", err
 	}
 
-	artists := spotifyArtistName
-	trackTitle := spotifyTrackName
-	albumTitle := spotifyAlbumName
-
-	fmt.Printf("Found track: %s - %s\n", artists, trackTitle)
-	fmt.Printf("Album: %s\n", albumTitle)
+	fmt.Printf("Found track: %s - %s\n", req.Tags.Artist, req.Tags.Title)
+	fmt.Printf("Album: %s\n", req.Tags.Album)
 
 	qualityInfo := "Standard"
 	if track.Hires {
@@ -362,7 +356,7 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 	fmt.Printf("Quality: %s\n", qualityInfo)
 
 	fmt.Println("Getting download URL...")
-	downloadURL, err := q.GetDownloadURL(track.ID, quality)
+	downloadURL, err := q.GetDownloadURL(track.ID, req.Quality)
 	if err != nil {
 		return "", fmt.Errorf("failed to get download URL: %w", err)
 	}
@@ -377,13 +371,8 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 	}
 	fmt.Printf("Download URL obtained: %s\n", urlPreview)
 
-	safeArtist := sanitizeFilename(artists)
-	safeTitle := sanitizeFilename(trackTitle)
-	safeAlbum := sanitizeFilename(albumTitle)
-	safeAlbumArtist := sanitizeFilename(spotifyAlbumArtist)
-
-	filename := buildQobuzFilename(safeTitle, safeArtist, safeAlbum, safeAlbumArtist, spotifyReleaseDate, spotifyTrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	filepath := filepath.Join(outputDir, filename)
+	filename := buildQobuzFilename(req, req.Tags.TrackNumber)
+	filepath := filepath.Join(req.OutputDir, filename)
 
 	if fileInfo, err := os.Stat(filepath); err == nil && fileInfo.Size() > 0 {
 		fmt.Printf("File already exists: %s (%.2f MB)\n", filepath, float64(fileInfo.Size())/(1024*1024))
@@ -391,7 +380,7 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 	}
 
 	fmt.Printf("Downloading FLAC file to: %s\n", filepath)
-	if err := q.DownloadFile(downloadURL, filepath); err != nil {
+	if err := q.DownloadFile(downloadURL, filepath, req.ItemID); err != nil {
 		return "", fmt.Errorf("failed to download file: %w", err)
 	}
 
@@ -399,10 +388,10 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = filepath + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -413,31 +402,86 @@ func (q *QobuzDownloader) DownloadByISRC(deezerISRC, outputDir, quality, filenam
 
 	fmt.Println("Embedding metadata and cover art...")
 
-	trackNumberToEmbed := spotifyTrackNumber
-	if trackNumberToEmbed == 0 {
-		trackNumberToEmbed = 1
-	}
-
-	metadata := Metadata{
-		Title:       trackTitle,
-		Artist:      artists,
-		Album:       albumTitle,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
-		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
-		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
-		Description: "https://github.com/afkarxyz/SpotiFLAC",
+	req.Tags.QobuzID = strconv.FormatInt(track.ID, 10)
+	if !isValidISRC(req.Tags.ISRC) {
+		req.Tags.ISRC = track.ISRC
 	}
 
-	if err := EmbedMetadata(filepath, metadata, coverPath); err != nil {
+	if err := EmbedMetadata(filepath, req.Tags.ToMetadata(), coverPath); err != nil {
 		return "", fmt.Errorf("failed to embed metadata: %w", err)
 	}
 
 	fmt.Println("Metadata embedded successfully!")
 	return filepath, nil
 }
+
+func (q *QobuzDownloader) Name() string {
+	return "qobuz"
+}
+
+func (q *QobuzDownloader) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		ResolveBySpotifyID: true,
+		ResolveByISRC:      true,
+		HiRes:              true,
+	}
+}
+
+func (q *QobuzDownloader) Qualities() []string {
+	return []string{"6", "7", "27"}
+}
+
+func (q *QobuzDownloader) ResolveTrack(req ProviderRequest) (string, error) {
+	if req.Tags.ISRC == "" && req.Tags.SpotifyID == "" {
+		return "", fmt.Errorf("spotify ID is required for Qobuz")
+	}
+
+	deezerISRC := req.Tags.ISRC
+	if len(deezerISRC) != 12 || !isValidISRC(deezerISRC) {
+		deezerISRC = ""
+	}
+
+	if deezerISRC == "" && req.Tags.SpotifyID != "" {
+		songlinkClient := NewSongLinkClient()
+		deezerURL, err := songlinkClient.GetDeezerURLFromSpotify(req.Tags.SpotifyID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get Deezer URL: %w", err)
+		}
+		deezerISRC, err = GetDeezerISRC(deezerURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to get ISRC from Deezer: %w", err)
+		}
+	}
+
+	if deezerISRC == "" {
+		return "", fmt.Errorf("ISRC is required for Qobuz (could not fetch from Deezer)")
+	}
+
+	return deezerISRC, nil
+}
+
+func (q *QobuzDownloader) ProbeQuality(trackRef string) (string, error) {
+	track, err := q.SearchByISRC(trackRef)
+	if err != nil {
+		return "", err
+	}
+
+	if !track.Hires {
+		return "6", nil
+	}
+	if track.MaximumSamplingRate > 96 {
+		return "27", nil
+	}
+	return "7", nil
+}
+
+func (q *QobuzDownloader) FetchTrack(trackRef string, req ProviderRequest) (string, error) {
+	quality := req.Quality
+	if quality == "" {
+		quality = "6"
+	}
+
+	trackReq := req.TrackRequest
+	trackReq.Quality = quality
+	return q.DownloadByISRC(trackRef, trackReq)
+}
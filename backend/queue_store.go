@@ -0,0 +1,175 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const queueBucket = "DownloadQueue"
+
+type PersistedDownload struct {
+	ID           string          `json:"id"`
+	TrackName    string          `json:"track_name"`
+	ArtistName   string          `json:"artist_name"`
+	AlbumName    string          `json:"album_name"`
+	Status       DownloadStatus  `json:"status"`
+	ErrorMessage string          `json:"error_message,omitempty"`
+	Request      json.RawMessage `json:"request"`
+	QueuedAt     int64           `json:"queued_at"`
+	UpdatedAt    int64           `json:"updated_at"`
+}
+
+func PersistDownloadRequest(id, trackName, artistName, albumName string, request interface{}) error {
+	if historyDB == nil {
+		return nil
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(queueBucket))
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().Unix()
+		item := PersistedDownload{
+			ID:         id,
+			TrackName:  trackName,
+			ArtistName: artistName,
+			AlbumName:  albumName,
+			Status:     StatusQueued,
+			Request:    payload,
+			QueuedAt:   now,
+			UpdatedAt:  now,
+		}
+
+		if existing := b.Get([]byte(id)); existing != nil {
+			var prev PersistedDownload
+			if err := json.Unmarshal(existing, &prev); err == nil {
+				item.Status = prev.Status
+				item.ErrorMessage = prev.ErrorMessage
+				item.QueuedAt = prev.QueuedAt
+			}
+		}
+
+		buf, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), buf)
+	})
+}
+
+func persistDownloadStatus(id string, status DownloadStatus, errorMsg string) {
+	if historyDB == nil {
+		return
+	}
+
+	err := historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(queueBucket))
+		if b == nil {
+			return nil
+		}
+
+		existing := b.Get([]byte(id))
+		if existing == nil {
+			return nil
+		}
+
+		var item PersistedDownload
+		if err := json.Unmarshal(existing, &item); err != nil {
+			return err
+		}
+
+		item.Status = status
+		item.ErrorMessage = errorMsg
+		item.UpdatedAt = time.Now().Unix()
+
+		buf, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(id), buf)
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to persist status %s for %s: %v\n", status, id, err)
+	}
+}
+
+func GetPersistedDownloads() ([]PersistedDownload, error) {
+	if historyDB == nil {
+		return nil, nil
+	}
+
+	var items []PersistedDownload
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(queueBucket))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var item PersistedDownload
+			if err := json.Unmarshal(v, &item); err != nil {
+				return nil
+			}
+
+			if item.Status == StatusDownloading {
+				item.Status = StatusQueued
+			}
+			items = append(items, item)
+			return nil
+		})
+	})
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].QueuedAt < items[j].QueuedAt
+	})
+
+	return items, err
+}
+
+func DeletePersistedDownloads(ids []string) error {
+	if historyDB == nil || len(ids) == 0 {
+		return nil
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(queueBucket))
+		if b == nil {
+			return nil
+		}
+
+		for _, id := range ids {
+			if err := b.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func ClearPersistedDownloads() error {
+	if historyDB == nil {
+		return nil
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket([]byte(queueBucket)) == nil {
+			return nil
+		}
+		if err := tx.DeleteBucket([]byte(queueBucket)); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket([]byte(queueBucket))
+		return err
+	})
+}
@@ -0,0 +1,202 @@
+package backend
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const resumableAttempts = 3
+
+func partFilePath(destPath string) string {
+	return destPath + ".part"
+}
+
+func partValidatorPath(partPath string) string {
+	return partPath + ".etag"
+}
+
+func DownloadResumable(client *http.Client, url, destPath, itemID string) (int64, error) {
+	return downloadResumable(client, nil, url, nil, destPath, itemID)
+}
+
+func DownloadResumableResponse(client *http.Client, resp *http.Response, destPath, itemID string) (int64, error) {
+	return downloadResumable(client, resp, resp.Request.URL.String(), resp.Request.Header, destPath, itemID)
+}
+
+func downloadResumable(client *http.Client, first *http.Response, url string, header http.Header, destPath, itemID string) (int64, error) {
+	partPath := partFilePath(destPath)
+
+	var lastErr error
+	trusted := false
+	for attempt := 1; attempt <= resumableAttempts; attempt++ {
+		if attempt > 1 {
+			fmt.Printf("\nRetrying download (attempt %d/%d): %v\n", attempt, resumableAttempts, lastErr)
+			time.Sleep(time.Duration(attempt-1) * time.Second)
+		}
+
+		var total int64
+		var err error
+		if first != nil {
+			total, err = storePartResponse(first, partPath, 0, itemID)
+			first.Body.Close()
+			first = nil
+		} else {
+			total, err = fetchIntoPart(client, url, header, partPath, itemID, trusted)
+		}
+		if err == nil {
+			if err := os.Rename(partPath, destPath); err != nil {
+				return 0, fmt.Errorf("failed to finalize file: %w", err)
+			}
+			os.Remove(partValidatorPath(partPath))
+			return total, nil
+		}
+
+		lastErr = err
+		trusted = true
+	}
+
+	return 0, lastErr
+}
+
+func fetchIntoPart(client *http.Client, url string, header http.Header, partPath, itemID string, trusted bool) (int64, error) {
+	validatorPath := partValidatorPath(partPath)
+
+	var offset int64
+	validator := ""
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+		if data, err := os.ReadFile(validatorPath); err == nil {
+			validator = strings.TrimSpace(string(data))
+		}
+		if validator == "" && !trusted {
+			offset = 0
+		}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	for key, values := range header {
+		if key != "Range" && key != "If-Range" {
+			req.Header[key] = values
+		}
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if validator != "" {
+			req.Header.Set("If-Range", validator)
+		}
+		fmt.Printf("Resuming download at %.2f MB\n", float64(offset)/(1024*1024))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return storePartResponse(resp, partPath, offset, itemID)
+}
+
+func storePartResponse(resp *http.Response, partPath string, offset int64, itemID string) (int64, error) {
+	validatorPath := partValidatorPath(partPath)
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, _, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			os.Remove(partPath)
+			return 0, fmt.Errorf("server returned unexpected range %q", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		offset = 0
+		flags |= os.O_TRUNC
+		validator := resp.Header.Get("ETag")
+		if validator == "" {
+			validator = resp.Header.Get("Last-Modified")
+		}
+		if validator != "" {
+			os.WriteFile(validatorPath, []byte(validator), 0644)
+		} else {
+			os.Remove(validatorPath)
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if ok && size == offset {
+			return offset, nil
+		}
+		os.Remove(partPath)
+		return 0, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	pw := NewProgressWriterWithID(out, itemID)
+	pw.total = offset
+	pw.lastPrinted = offset
+	pw.lastBytes = offset
+
+	n, err := io.Copy(pw, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return 0, fmt.Errorf("incomplete download: got %d of %d bytes", n, resp.ContentLength)
+	}
+
+	return offset + n, nil
+}
+
+func parseContentRange(header string) (start, size int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, false
+	}
+
+	spec := strings.TrimPrefix(header, "bytes ")
+	slash := strings.LastIndex(spec, "/")
+	if slash < 0 {
+		return 0, 0, false
+	}
+
+	size = -1
+	if total := spec[slash+1:]; total != "*" {
+		parsed, err := strconv.ParseInt(total, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		size = parsed
+	}
+
+	rangePart := spec[:slash]
+	if rangePart == "*" {
+		return 0, size, true
+	}
+
+	dash := strings.Index(rangePart, "-")
+	if dash < 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(rangePart[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, size, true
+}
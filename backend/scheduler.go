@@ -0,0 +1,138 @@
+package backend
+
+import (
+	"sync"
+)
+
+const (
+	defaultMaxWorkers          = 4
+	defaultProviderConcurrency = 2
+)
+
+type DownloadJob struct {
+	ItemID string
+	Run    func()
+}
+
+type DownloadScheduler struct {
+	mu         sync.Mutex
+	cond       *sync.Cond
+	wg         sync.WaitGroup
+	maxWorkers int
+	limits     map[string]int
+	pending    []DownloadJob
+	active     map[string]int
+	running    int
+}
+
+func NewDownloadScheduler(maxWorkers int) *DownloadScheduler {
+	if maxWorkers <= 0 {
+		maxWorkers = defaultMaxWorkers
+	}
+
+	s := &DownloadScheduler{
+		maxWorkers: maxWorkers,
+		limits:     make(map[string]int),
+		active:     make(map[string]int),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *DownloadScheduler) SetMaxWorkers(n int) {
+	if n <= 0 {
+		n = defaultMaxWorkers
+	}
+
+	s.mu.Lock()
+	s.maxWorkers = n
+	s.mu.Unlock()
+
+	s.dispatch()
+}
+
+func (s *DownloadScheduler) SetProviderConcurrency(service string, n int) {
+	s.mu.Lock()
+	if n <= 0 {
+		delete(s.limits, service)
+	} else {
+		s.limits[service] = n
+	}
+	s.mu.Unlock()
+
+	s.cond.Broadcast()
+}
+
+func (s *DownloadScheduler) Enqueue(jobs ...DownloadJob) {
+	s.mu.Lock()
+	s.pending = append(s.pending, jobs...)
+	s.wg.Add(len(jobs))
+	s.mu.Unlock()
+
+	s.dispatch()
+}
+
+func (s *DownloadScheduler) Wait() {
+	s.wg.Wait()
+}
+
+func (s *DownloadScheduler) Stats() (pending, running int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending), s.running
+}
+
+func (s *DownloadScheduler) providerLimit(service string) int {
+	if n, ok := s.limits[service]; ok {
+		return n
+	}
+	return defaultProviderConcurrency
+}
+
+func (s *DownloadScheduler) AcquireProvider(service string) func() {
+	s.mu.Lock()
+	for s.active[service] >= s.providerLimit(service) {
+		s.cond.Wait()
+	}
+	s.active[service]++
+	s.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mu.Lock()
+			s.active[service]--
+			s.mu.Unlock()
+			s.cond.Broadcast()
+		})
+	}
+}
+
+func (s *DownloadScheduler) dispatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) > 0 && s.running < s.maxWorkers {
+		job := s.pending[0]
+		s.pending = s.pending[1:]
+		s.running++
+		go s.run(job)
+	}
+}
+
+func (s *DownloadScheduler) run(job DownloadJob) {
+	defer func() {
+		s.mu.Lock()
+		s.running--
+		s.mu.Unlock()
+
+		s.wg.Done()
+		s.dispatch()
+	}()
+
+	if item, ok := GetDownloadItem(job.ItemID); ok && item.Status != StatusQueued {
+		return
+	}
+
+	job.Run()
+}
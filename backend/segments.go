@@ -0,0 +1,162 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSegmentWorkers  = 4
+	defaultSegmentAttempts = 4
+	defaultSegmentBackoff  = 500 * time.Millisecond
+)
+
+type SegmentFetcher struct {
+	Client   *http.Client
+	Workers  int
+	Attempts int
+	Backoff  time.Duration
+	ItemID   string
+}
+
+type segmentResult struct {
+	data []byte
+	err  error
+}
+
+func NewSegmentFetcher(client *http.Client, itemID string) *SegmentFetcher {
+	return &SegmentFetcher{
+		Client:   client,
+		Workers:  defaultSegmentWorkers,
+		Attempts: defaultSegmentAttempts,
+		Backoff:  defaultSegmentBackoff,
+		ItemID:   itemID,
+	}
+}
+
+func (f *SegmentFetcher) FetchAll(urls []string, w io.Writer) (int64, error) {
+	workers := f.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	results := make([]chan segmentResult, len(urls))
+	for i := range results {
+		results[i] = make(chan segmentResult, 1)
+	}
+
+	slots := make(chan struct{}, workers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, url := range urls {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			wg.Add(1)
+			go func(idx int, segmentURL string) {
+				defer wg.Done()
+				data, err := f.fetchSegment(ctx, segmentURL)
+				results[idx] <- segmentResult{data: data, err: err}
+			}(i, url)
+		}
+	}()
+
+	var totalBytes, lastBytes int64
+	lastTime := time.Now()
+	for i := range urls {
+		res := <-results[i]
+		<-slots
+
+		if res.err != nil {
+			return totalBytes, fmt.Errorf("segment %d: %w", i, res.err)
+		}
+
+		n, err := w.Write(res.data)
+		totalBytes += int64(n)
+		if err != nil {
+			return totalBytes, fmt.Errorf("failed to write segment %d: %w", i, err)
+		}
+
+		mbDownloaded := float64(totalBytes) / (1024 * 1024)
+		now := time.Now()
+		var speedMBps float64
+		if timeDiff := now.Sub(lastTime).Seconds(); timeDiff > 0.1 {
+			speedMBps = (float64(totalBytes-lastBytes) / (1024 * 1024)) / timeDiff
+			lastTime = now
+			lastBytes = totalBytes
+		}
+		UpdateItemProgress(f.ItemID, mbDownloaded, speedMBps)
+
+		fmt.Printf("\rDownloading: %.2f MB (%d/%d segments)", mbDownloaded, i+1, len(urls))
+	}
+
+	return totalBytes, nil
+}
+
+func (f *SegmentFetcher) fetchSegment(ctx context.Context, url string) ([]byte, error) {
+	attempts := f.Attempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(f.Backoff * time.Duration(1<<(attempt-1))):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		data, retry, err := f.fetchOnce(ctx, url)
+		if err == nil {
+			return data, nil
+		}
+		if !retry {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("failed after %d attempts: %w", attempts, lastErr)
+}
+
+func (f *SegmentFetcher) fetchOnce(ctx context.Context, url string) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retry, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, ctx.Err() == nil, err
+	}
+	return data, false, nil
+}
@@ -0,0 +1,190 @@
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type segmentServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	attempts map[int][]time.Time
+}
+
+func newSegmentServer(t *testing.T, count int, handle func(idx, attempt int, w http.ResponseWriter, r *http.Request) bool) *segmentServer {
+	s := &segmentServer{attempts: make(map[int][]time.Time)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idx, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/seg/"))
+		if err != nil || idx < 0 || idx >= count {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.mu.Lock()
+		s.attempts[idx] = append(s.attempts[idx], time.Now())
+		attempt := len(s.attempts[idx])
+		s.mu.Unlock()
+
+		if handle(idx, attempt, w, r) {
+			return
+		}
+		w.Write(segmentPayload(idx))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *segmentServer) urls(count int) []string {
+	urls := make([]string, count)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/seg/%d", s.URL, i)
+	}
+	return urls
+}
+
+func (s *segmentServer) attemptTimes(idx int) []time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]time.Time(nil), s.attempts[idx]...)
+}
+
+func segmentPayload(idx int) []byte {
+	return bytes.Repeat([]byte{byte('a' + idx%26)}, 1024+idx)
+}
+
+func TestSegmentFetcherOrderAndRetries(t *testing.T) {
+	const count = 12
+	const backoff = 20 * time.Millisecond
+	flaky := map[int]int{2: 1, 5: 2, 9: 1}
+
+	server := newSegmentServer(t, count, func(idx, attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if attempt <= flaky[idx] {
+			if idx == 5 {
+				w.WriteHeader(http.StatusTooManyRequests)
+			} else {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return true
+		}
+		time.Sleep(time.Duration(count-idx) * 3 * time.Millisecond)
+		return false
+	})
+
+	fetcher := NewSegmentFetcher(server.Client(), "")
+	fetcher.Backoff = backoff
+
+	var out bytes.Buffer
+	n, err := fetcher.FetchAll(server.urls(count), &out)
+	if err != nil {
+		t.Fatalf("FetchAll: %v", err)
+	}
+
+	var want bytes.Buffer
+	for i := 0; i < count; i++ {
+		want.Write(segmentPayload(i))
+	}
+	if n != int64(want.Len()) || !bytes.Equal(out.Bytes(), want.Bytes()) {
+		t.Fatalf("assembled %d bytes out of order or incomplete, want %d", n, want.Len())
+	}
+
+	for idx := 0; idx < count; idx++ {
+		times := server.attemptTimes(idx)
+		if got, want := len(times), flaky[idx]+1; got != want {
+			t.Errorf("segment %d: %d attempts, want %d", idx, got, want)
+		}
+		for attempt := 1; attempt < len(times); attempt++ {
+			minDelay := backoff * time.Duration(1<<(attempt-1))
+			if gap := times[attempt].Sub(times[attempt-1]); gap < minDelay {
+				t.Errorf("segment %d retry %d after %s, want backoff of at least %s", idx, attempt, gap, minDelay)
+			}
+		}
+	}
+}
+
+func TestSegmentFetcherGivesUpAfterAttempts(t *testing.T) {
+	server := newSegmentServer(t, 3, func(idx, attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if idx == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return true
+		}
+		return false
+	})
+
+	fetcher := NewSegmentFetcher(server.Client(), "")
+	fetcher.Backoff = time.Millisecond
+	fetcher.Attempts = 3
+
+	var out bytes.Buffer
+	if _, err := fetcher.FetchAll(server.urls(3), &out); err == nil {
+		t.Fatal("expected an error for a segment that never succeeds")
+	}
+	if got := len(server.attemptTimes(1)); got != 3 {
+		t.Errorf("segment 1 attempted %d times, want 3", got)
+	}
+	if !bytes.Equal(out.Bytes(), segmentPayload(0)) {
+		t.Errorf("wrote %d bytes, want only segment 0", out.Len())
+	}
+}
+
+func TestSegmentFetcherPermanentFailureDoesNotLeak(t *testing.T) {
+	const count = 40
+
+	server := newSegmentServer(t, count, func(idx, attempt int, w http.ResponseWriter, r *http.Request) bool {
+		if idx == 3 {
+			w.WriteHeader(http.StatusNotFound)
+			return true
+		}
+		if idx > 3 {
+			select {
+			case <-r.Context().Done():
+				return true
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return false
+	})
+
+	client := server.Client()
+	before := runtime.NumGoroutine()
+
+	fetcher := NewSegmentFetcher(client, "")
+	fetcher.Backoff = time.Millisecond
+
+	var out bytes.Buffer
+	_, err := fetcher.FetchAll(server.urls(count), &out)
+	if err == nil || !strings.Contains(err.Error(), "segment 3") {
+		t.Fatalf("expected segment 3 to abort the download, got %v", err)
+	}
+	if got := len(server.attemptTimes(3)); got != 1 {
+		t.Errorf("permanent failure retried: %d attempts", got)
+	}
+
+	requested := 0
+	for idx := 4; idx < count; idx++ {
+		if len(server.attemptTimes(idx)) > 0 {
+			requested++
+		}
+	}
+	if requested >= count-4 {
+		t.Errorf("all %d remaining segments were requested after the abort", requested)
+	}
+
+	client.CloseIdleConnections()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		client.CloseIdleConnections()
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked: %d before, %d after", before, after)
+	}
+}
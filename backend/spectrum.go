@@ -2,10 +2,28 @@ package backend
 
 import (
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
 	"math"
-	"math/cmplx"
+	"math/bits"
+	"os"
+	pathfilepath "path/filepath"
+	"strings"
+)
 
-	"github.com/mewkiz/flac"
+const (
+	defaultFFTSize            = 8192
+	defaultTimeSlices         = 300
+	minFFTSize                = 256
+	spectrogramRangeDB        = 90.0
+	spectrumFloorPower        = 1e-20
+	spectrumWindowHann        = "hann"
+	spectrumWindowHamming     = "hamming"
+	spectrumWindowBlackman    = "blackman"
+	spectrumWindowRectangular = "rectangular"
+	spectrogramPNGSuffix      = ".spectrogram.png"
 )
 
 type SpectrumData struct {
@@ -21,161 +39,417 @@ type TimeSlice struct {
 	Magnitudes []float64 `json:"magnitudes"`
 }
 
+type SpectrogramOptions struct {
+	FFTSize    int    `json:"fft_size,omitempty"`
+	HopSize    int    `json:"hop_size,omitempty"`
+	Window     string `json:"window,omitempty"`
+	TimeSlices int    `json:"time_slices,omitempty"`
+	FreqBins   int    `json:"freq_bins,omitempty"`
+}
+
+func (o SpectrogramOptions) normalized() SpectrogramOptions {
+	if o.FFTSize <= 0 {
+		o.FFTSize = defaultFFTSize
+	}
+	if o.FFTSize < minFFTSize {
+		o.FFTSize = minFFTSize
+	}
+	if o.FFTSize&(o.FFTSize-1) != 0 {
+		o.FFTSize = 1 << bits.Len(uint(o.FFTSize))
+	}
+	if o.HopSize <= 0 {
+		o.HopSize = o.FFTSize
+	}
+	if o.Window == "" {
+		o.Window = spectrumWindowHann
+	}
+	if o.TimeSlices <= 0 {
+		o.TimeSlices = defaultTimeSlices
+	}
+	if o.FreqBins <= 0 || o.FreqBins > o.FFTSize/2 {
+		o.FreqBins = o.FFTSize / 2
+	}
+	return o
+}
+
 func AnalyzeSpectrum(filepath string) (*SpectrumData, error) {
+	return AnalyzeSpectrumWithOptions(filepath, SpectrogramOptions{})
+}
 
-	stream, err := flac.ParseFile(filepath)
+func AnalyzeSpectrumWithOptions(filepath string, opts SpectrogramOptions) (*SpectrumData, error) {
+	dec, err := OpenAudioDecoder(filepath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse FLAC: %w", err)
+		return nil, err
 	}
-	defer stream.Close()
-
-	info := stream.Info
-	sampleRate := int(info.SampleRate)
-	channels := int(info.NChannels)
+	defer dec.Close()
 
-	samples, err := readSamples(stream, channels)
+	stft, err := newSTFT(dec.Info().SampleRate, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read samples: %w", err)
+		return nil, err
 	}
 
-	if len(samples) == 0 {
+	for {
+		frame, err := dec.ReadFrame()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read samples: %w", err)
+		}
+		stft.pushFrame(frame)
+	}
+
+	spectrum := stft.finish()
+	if len(spectrum.TimeSlices) == 0 {
 		return nil, fmt.Errorf("no audio samples found")
 	}
 
-	return calculateSpectrum(samples, sampleRate), nil
+	return spectrum, nil
 }
 
-func readSamples(stream *flac.Stream, channels int) ([]float64, error) {
-	var allSamples []float64
-	maxSamples := 10 * 1024 * 1024
+type stft struct {
+	opts       SpectrogramOptions
+	sampleRate int
+	plan       *fftPlan
+	window     []float64
+	work       []complex128
+	binGroup   int
+
+	buf     []float64
+	skip    int
+	frames  int
+	samples int64
+
+	bucketWidth  int
+	bucketPower  [][]float64
+	bucketCounts []int
+	bucketStarts []int
+}
 
-	for {
-		frame, err := stream.ParseNext()
-		if err != nil {
+func newSTFT(sampleRate int, opts SpectrogramOptions) (*stft, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d", sampleRate)
+	}
 
-			break
-		}
+	opts = opts.normalized()
+	window, err := makeWindow(opts.Window, opts.FFTSize)
+	if err != nil {
+		return nil, err
+	}
 
-		for i := 0; i < frame.Subframes[0].NSamples; i++ {
-			var sample float64
+	return &stft{
+		opts:        opts,
+		sampleRate:  sampleRate,
+		plan:        newFFTPlan(opts.FFTSize),
+		window:      window,
+		work:        make([]complex128, opts.FFTSize),
+		binGroup:    (opts.FFTSize / 2) / opts.FreqBins,
+		buf:         make([]float64, 0, opts.FFTSize),
+		bucketWidth: 1,
+	}, nil
+}
 
-			for ch := 0; ch < channels; ch++ {
-				sample += float64(frame.Subframes[ch].Samples[i])
-			}
-			sample /= float64(channels)
+func (s *stft) pushFrame(frame [][]float64) {
+	channels := float64(len(frame))
+	for i := range frame[0] {
+		var sample float64
+		for ch := range frame {
+			sample += frame[ch][i]
+		}
+		s.push(sample / channels)
+	}
+}
 
-			allSamples = append(allSamples, sample)
+func (s *stft) push(sample float64) {
+	s.samples++
+	if s.skip > 0 {
+		s.skip--
+		return
+	}
 
-			if len(allSamples) >= maxSamples {
-				return allSamples, nil
-			}
-		}
+	s.buf = append(s.buf, sample)
+	if len(s.buf) < s.opts.FFTSize {
+		return
 	}
 
-	return allSamples, nil
-}
+	s.processFrame()
 
-func calculateSpectrum(samples []float64, sampleRate int) *SpectrumData {
-	fftSize := 8192
-	numTimeSlices := 300
+	if s.opts.HopSize < s.opts.FFTSize {
+		n := copy(s.buf, s.buf[s.opts.HopSize:])
+		s.buf = s.buf[:n]
+	} else {
+		s.buf = s.buf[:0]
+		s.skip = s.opts.HopSize - s.opts.FFTSize
+	}
+}
 
-	duration := float64(len(samples)) / float64(sampleRate)
+func (s *stft) processFrame() {
+	for i := range s.work {
+		var v float64
+		if i < len(s.buf) {
+			v = s.buf[i] * s.window[i]
+		}
+		s.work[i] = complex(v, 0)
+	}
+	s.plan.transform(s.work)
 
-	samplesPerSlice := len(samples) / numTimeSlices
-	if samplesPerSlice < fftSize {
-		samplesPerSlice = fftSize
-		numTimeSlices = len(samples) / fftSize
+	idx := s.frames / s.bucketWidth
+	if idx >= len(s.bucketPower) {
+		if len(s.bucketPower) == 2*s.opts.TimeSlices {
+			s.mergeBuckets()
+			idx = s.frames / s.bucketWidth
+		}
+		outBins := (s.opts.FFTSize / 2) / s.binGroup
+		s.bucketPower = append(s.bucketPower, make([]float64, outBins))
+		s.bucketCounts = append(s.bucketCounts, 0)
+		s.bucketStarts = append(s.bucketStarts, s.frames)
 	}
 
-	timeSlices := make([]TimeSlice, 0, numTimeSlices)
-	freqBins := fftSize / 2
-	maxFreq := float64(sampleRate) / 2.0
+	power := s.bucketPower[idx]
+	for j := range power {
+		for k := j * s.binGroup; k < (j+1)*s.binGroup; k++ {
+			re, im := real(s.work[k]), imag(s.work[k])
+			power[j] += re*re + im*im
+		}
+	}
+	s.bucketCounts[idx] += s.binGroup
+	s.frames++
+}
 
-	for i := 0; i < numTimeSlices; i++ {
-		startIdx := i * samplesPerSlice
-		if startIdx+fftSize > len(samples) {
-			break
+func (s *stft) mergeBuckets() {
+	half := len(s.bucketPower) / 2
+	for i := 0; i < half; i++ {
+		a, b := s.bucketPower[2*i], s.bucketPower[2*i+1]
+		for j := range a {
+			a[j] += b[j]
 		}
+		s.bucketPower[i] = a
+		s.bucketCounts[i] = s.bucketCounts[2*i] + s.bucketCounts[2*i+1]
+		s.bucketStarts[i] = s.bucketStarts[2*i]
+	}
+	s.bucketPower = s.bucketPower[:half]
+	s.bucketCounts = s.bucketCounts[:half]
+	s.bucketStarts = s.bucketStarts[:half]
+	s.bucketWidth *= 2
+}
 
-		window := samples[startIdx : startIdx+fftSize]
+func (s *stft) finish() *SpectrumData {
+	if s.frames == 0 && len(s.buf) > 0 {
+		s.processFrame()
+	}
 
-		windowedSamples := applyHannWindow(window)
+	outBins := (s.opts.FFTSize / 2) / s.binGroup
+	data := &SpectrumData{
+		SampleRate: s.sampleRate,
+		FreqBins:   outBins,
+		Duration:   float64(s.samples) / float64(s.sampleRate),
+		MaxFreq:    float64(s.sampleRate) / 2.0,
+	}
 
-		spectrum := fft(windowedSamples)
+	n := len(s.bucketPower)
+	slices := n
+	if slices > s.opts.TimeSlices {
+		slices = s.opts.TimeSlices
+	}
 
-		magnitudes := make([]float64, freqBins)
-		for j := 0; j < freqBins; j++ {
-			magnitude := cmplx.Abs(spectrum[j])
+	data.TimeSlices = make([]TimeSlice, 0, slices)
+	for i := 0; i < slices; i++ {
+		lo, hi := i*n/slices, (i+1)*n/slices
 
-			if magnitude < 1e-10 {
-				magnitude = 1e-10
+		magnitudes := make([]float64, outBins)
+		count := 0
+		for b := lo; b < hi; b++ {
+			for j, p := range s.bucketPower[b] {
+				magnitudes[j] += p
 			}
-			magnitudes[j] = 20 * math.Log10(magnitude)
+			count += s.bucketCounts[b]
+		}
+		for j, p := range magnitudes {
+			p /= float64(count)
+			if p < spectrumFloorPower {
+				p = spectrumFloorPower
+			}
+			magnitudes[j] = 10 * math.Log10(p)
 		}
 
-		timeSlice := TimeSlice{
-			Time:       float64(startIdx) / float64(sampleRate),
+		data.TimeSlices = append(data.TimeSlices, TimeSlice{
+			Time:       float64(s.bucketStarts[lo]*s.opts.HopSize) / float64(s.sampleRate),
 			Magnitudes: magnitudes,
+		})
+	}
+
+	return data
+}
+
+func makeWindow(name string, n int) ([]float64, error) {
+	window := make([]float64, n)
+	denom := float64(n - 1)
+
+	for i := range window {
+		x := 2.0 * math.Pi * float64(i) / denom
+		switch strings.ToLower(name) {
+		case spectrumWindowHann:
+			window[i] = 0.5 * (1.0 - math.Cos(x))
+		case spectrumWindowHamming:
+			window[i] = 0.54 - 0.46*math.Cos(x)
+		case spectrumWindowBlackman:
+			window[i] = 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+		case spectrumWindowRectangular:
+			window[i] = 1
+		default:
+			return nil, fmt.Errorf("unknown window function: %s", name)
 		}
-		timeSlices = append(timeSlices, timeSlice)
 	}
 
-	return &SpectrumData{
-		TimeSlices: timeSlices,
-		SampleRate: sampleRate,
-		FreqBins:   freqBins,
-		Duration:   duration,
-		MaxFreq:    maxFreq,
+	return window, nil
+}
+
+type fftPlan struct {
+	n        int
+	twiddles []complex128
+	reversed []int
+}
+
+func newFFTPlan(n int) *fftPlan {
+	plan := &fftPlan{
+		n:        n,
+		twiddles: make([]complex128, n/2),
+		reversed: make([]int, n),
+	}
+
+	for k := range plan.twiddles {
+		angle := -2 * math.Pi * float64(k) / float64(n)
+		plan.twiddles[k] = complex(math.Cos(angle), math.Sin(angle))
 	}
+
+	shift := bits.UintSize - bits.Len(uint(n-1))
+	for i := range plan.reversed {
+		plan.reversed[i] = int(bits.Reverse(uint(i)) >> shift)
+	}
+
+	return plan
 }
 
-func applyHannWindow(samples []float64) []float64 {
-	n := len(samples)
-	windowed := make([]float64, n)
+func (p *fftPlan) transform(x []complex128) {
+	for i, j := range p.reversed {
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
 
-	for i := 0; i < n; i++ {
-		window := 0.5 * (1.0 - math.Cos(2.0*math.Pi*float64(i)/float64(n-1)))
-		windowed[i] = samples[i] * window
+	for size := 2; size <= p.n; size <<= 1 {
+		half := size / 2
+		step := p.n / size
+		for start := 0; start < p.n; start += size {
+			for k := 0; k < half; k++ {
+				t := p.twiddles[k*step] * x[start+k+half]
+				x[start+k+half] = x[start+k] - t
+				x[start+k] += t
+			}
+		}
 	}
+}
 
-	return windowed
+func spectrogramOutputPath(filepath string) string {
+	return strings.TrimSuffix(filepath, pathfilepath.Ext(filepath)) + spectrogramPNGSuffix
 }
 
-func fft(samples []float64) []complex128 {
-	n := len(samples)
+func ExportSpectrogramPNG(filepath, outputPath string, opts SpectrogramOptions) (string, error) {
+	spectrum, err := AnalyzeSpectrumWithOptions(filepath, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if outputPath == "" {
+		outputPath = spectrogramOutputPath(filepath)
+	}
 
-	x := make([]complex128, n)
-	for i := 0; i < n; i++ {
-		x[i] = complex(samples[i], 0)
+	if err := WriteSpectrogramPNG(spectrum, outputPath); err != nil {
+		return "", err
 	}
 
-	return fftRecursive(x)
+	return outputPath, nil
 }
 
-func fftRecursive(x []complex128) []complex128 {
-	n := len(x)
+func WriteSpectrogramPNG(spectrum *SpectrumData, outputPath string) error {
+	if spectrum == nil || len(spectrum.TimeSlices) == 0 || spectrum.FreqBins == 0 {
+		return fmt.Errorf("spectrum is empty")
+	}
 
-	if n <= 1 {
-		return x
+	maxDB := math.Inf(-1)
+	minDB := 0.0
+	for _, slice := range spectrum.TimeSlices {
+		for _, db := range slice.Magnitudes {
+			if db > maxDB {
+				maxDB = db
+			}
+			if db < minDB && db > -200 {
+				minDB = db
+			}
+		}
+	}
+	minDB = math.Max(minDB, maxDB-spectrogramRangeDB)
+	dbRange := maxDB - minDB
+	if dbRange <= 0 {
+		dbRange = 1
 	}
 
-	even := make([]complex128, n/2)
-	odd := make([]complex128, n/2)
+	width, height := len(spectrum.TimeSlices), spectrum.FreqBins
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x, slice := range spectrum.TimeSlices {
+		for f := 0; f < height && f < len(slice.Magnitudes); f++ {
+			intensity := math.Max(0, math.Min(1, (slice.Magnitudes[f]-minDB)/dbRange))
+			img.Set(x, height-1-f, spectrogramColor(intensity))
+		}
+	}
 
-	for i := 0; i < n/2; i++ {
-		even[i] = x[2*i]
-		odd[i] = x[2*i+1]
+	if err := os.MkdirAll(pathfilepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	evenFFT := fftRecursive(even)
-	oddFFT := fftRecursive(odd)
+	out, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create PNG: %w", err)
+	}
 
-	result := make([]complex128, n)
-	for k := 0; k < n/2; k++ {
-		t := cmplx.Exp(complex(0, -2*math.Pi*float64(k)/float64(n))) * oddFFT[k]
-		result[k] = evenFFT[k] + t
-		result[k+n/2] = evenFFT[k] - t
+	if err := png.Encode(out, img); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to encode PNG: %w", err)
 	}
 
-	return result
+	return out.Close()
+}
+
+var spectrogramPalette = []struct {
+	pos     float64
+	r, g, b float64
+}{
+	{0.00, 0, 0, 0},
+	{0.08, 0, 0, 80},
+	{0.18, 50, 30, 255},
+	{0.28, 200, 0, 200},
+	{0.40, 255, 0, 0},
+	{0.52, 255, 100, 0},
+	{0.65, 255, 180, 0},
+	{0.78, 255, 235, 30},
+	{0.90, 255, 255, 130},
+	{1.00, 255, 255, 255},
+}
+
+func spectrogramColor(intensity float64) color.RGBA {
+	for i := 1; i < len(spectrogramPalette); i++ {
+		hi := spectrogramPalette[i]
+		if intensity > hi.pos && i < len(spectrogramPalette)-1 {
+			continue
+		}
+		lo := spectrogramPalette[i-1]
+		t := (intensity - lo.pos) / (hi.pos - lo.pos)
+		return color.RGBA{
+			R: uint8(lo.r + t*(hi.r-lo.r)),
+			G: uint8(lo.g + t*(hi.g-lo.g)),
+			B: uint8(lo.b + t*(hi.b-lo.b)),
+			A: 255,
+		}
+	}
+	return color.RGBA{A: 255}
 }
@@ -1203,6 +1203,7 @@ func extractRelease(release map[string]interface{}) map[string]interface{} {
 		"cover": cover,
 		"date":  releaseDate,
 		"year":  year,
+		"type":  strings.ToLower(getString(release, "type")),
 	}
 }
 
@@ -266,6 +266,7 @@ type apiArtistResponse struct {
 			Cover string `json:"cover"`
 			Date  string `json:"date"`
 			Year  int    `json:"year"`
+			Type  string `json:"type"`
 		} `json:"all"`
 		Total int `json:"total"`
 	} `json:"discography"`
@@ -1009,10 +1010,15 @@ func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context,
 
 		}
 
+		albumType := alb.Type
+		if albumType == "" {
+			albumType = "album"
+		}
+
 		albumList = append(albumList, DiscographyAlbumMetadata{
 			ID:          alb.ID,
 			Name:        alb.Name,
-			AlbumType:   "album",
+			AlbumType:   albumType,
 			ReleaseDate: alb.Date,
 			TotalTracks: 0,
 			Artists:     raw.Name,
@@ -1051,7 +1057,7 @@ func (c *SpotifyMetadataClient) formatArtistDiscographyData(ctx context.Context,
 				Name:        tr.Name,
 				AlbumName:   albumData.Name,
 				AlbumArtist: albumData.Artists,
-				AlbumType:   "album",
+				AlbumType:   albumType,
 				DurationMS:  durationMS,
 				Images:      albumData.Cover,
 				ReleaseDate: albumData.ReleaseDate,
@@ -0,0 +1,477 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	syncJobsBucket     = "SyncJobs"
+	syncArchiveDirName = "_archive"
+
+	SyncStatusDownloaded = "downloaded"
+	SyncStatusExisting   = "existing"
+	SyncStatusFailed     = "failed"
+	SyncStatusArchived   = "archived"
+	SyncStatusRemoved    = "removed"
+)
+
+type SyncJob struct {
+	ID             string                    `json:"id"`
+	Name           string                    `json:"name"`
+	URL            string                    `json:"url"`
+	SourceType     string                    `json:"source_type"`
+	OutputDir      string                    `json:"output_dir"`
+	ArchiveRemoved bool                      `json:"archive_removed"`
+	ArchiveDir     string                    `json:"archive_dir,omitempty"`
+	WritePlaylist  bool                      `json:"write_playlist,omitempty"`
+	Request        json.RawMessage           `json:"request,omitempty"`
+	Tracks         map[string]SyncTrackState `json:"tracks,omitempty"`
+	CreatedAt      int64                     `json:"created_at"`
+	LastRunAt      int64                     `json:"last_run_at,omitempty"`
+	LastReport     *SyncReport               `json:"last_report,omitempty"`
+}
+
+type SyncTrackState struct {
+	SpotifyID string `json:"spotify_id"`
+	Title     string `json:"title"`
+	Artist    string `json:"artist"`
+	Position  int    `json:"position"`
+	Path      string `json:"path"`
+}
+
+type SyncTrackResult struct {
+	SpotifyID string `json:"spotify_id"`
+	Title     string `json:"title"`
+	Artist    string `json:"artist"`
+	Position  int    `json:"position,omitempty"`
+	Status    string `json:"status"`
+	Path      string `json:"path,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type SyncReport struct {
+	JobID      string            `json:"job_id"`
+	SourceName string            `json:"source_name"`
+	StartedAt  int64             `json:"started_at"`
+	FinishedAt int64             `json:"finished_at"`
+	Total      int               `json:"total"`
+	Downloaded int               `json:"downloaded"`
+	Existing   int               `json:"existing"`
+	Failed     int               `json:"failed"`
+	Archived   int               `json:"archived"`
+	Removed    int               `json:"removed"`
+	Playlist   string            `json:"playlist,omitempty"`
+	Tracks     []SyncTrackResult `json:"tracks"`
+}
+
+type SyncDownload struct {
+	Track    AlbumTrackMetadata
+	Position int
+}
+
+type SyncDownloadFunc func(downloads []SyncDownload) (itemIDs []string)
+
+var (
+	syncRunningLock sync.Mutex
+	syncRunning     = make(map[string]bool)
+)
+
+func CreateSyncJob(job SyncJob) (*SyncJob, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("sync database is not initialized")
+	}
+
+	parsed, err := parseSpotifyURI(job.URL)
+	if err != nil {
+		return nil, err
+	}
+	if parsed.Type != "playlist" && parsed.Type != "album" {
+		return nil, fmt.Errorf("only playlist and album URLs can be synced, got %s", parsed.Type)
+	}
+	if job.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+
+	job.ID = fmt.Sprintf("%s-%d", parsed.ID, time.Now().UnixNano())
+	job.SourceType = parsed.Type
+	job.OutputDir = NormalizePath(job.OutputDir)
+	job.Tracks = nil
+	job.CreatedAt = time.Now().Unix()
+	job.LastRunAt = 0
+	job.LastReport = nil
+
+	if err := SaveSyncJob(job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func SaveSyncJob(job SyncJob) error {
+	if historyDB == nil {
+		return fmt.Errorf("sync database is not initialized")
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(syncJobsBucket))
+		if err != nil {
+			return err
+		}
+		buf, err := json.Marshal(job)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(job.ID), buf)
+	})
+}
+
+func GetSyncJob(id string) (*SyncJob, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("sync database is not initialized")
+	}
+
+	var job *SyncJob
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(syncJobsBucket))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(id))
+		if v == nil {
+			return nil
+		}
+		job = &SyncJob{}
+		return json.Unmarshal(v, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, fmt.Errorf("sync job not found: %s", id)
+	}
+	return job, nil
+}
+
+func ListSyncJobs() ([]SyncJob, error) {
+	if historyDB == nil {
+		return nil, fmt.Errorf("sync database is not initialized")
+	}
+
+	jobs := []SyncJob{}
+	err := historyDB.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(syncJobsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var job SyncJob
+			if err := json.Unmarshal(v, &job); err == nil {
+				jobs = append(jobs, job)
+			}
+			return nil
+		})
+	})
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt < jobs[j].CreatedAt
+	})
+	return jobs, err
+}
+
+func DeleteSyncJob(id string) error {
+	if historyDB == nil {
+		return fmt.Errorf("sync database is not initialized")
+	}
+
+	return historyDB.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(syncJobsBucket))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(id))
+	})
+}
+
+func FetchSyncSource(ctx context.Context, spotifyURL string) (string, []AlbumTrackMetadata, error) {
+	parsed, err := parseSpotifyURI(spotifyURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	client := NewSpotifyMetadataClient()
+	switch parsed.Type {
+	case "playlist":
+		raw, err := client.fetchPlaylist(ctx, parsed.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		payload := client.formatPlaylistData(raw)
+		return raw.Name, payload.TrackList, nil
+	case "album":
+		raw, err := client.fetchAlbum(ctx, parsed.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		payload, err := client.formatAlbumData(raw)
+		if err != nil {
+			return "", nil, err
+		}
+		return payload.AlbumInfo.Name, payload.TrackList, nil
+	default:
+		return "", nil, fmt.Errorf("only playlist and album URLs can be synced, got %s", parsed.Type)
+	}
+}
+
+func findSyncedFile(job *SyncJob, track AlbumTrackMetadata) string {
+	if state, ok := job.Tracks[track.SpotifyID]; ok && state.Path != "" {
+		if _, err := os.Stat(state.Path); err == nil {
+			return state.Path
+		}
+	}
+
+	match, err := FindLibraryTrack(LibraryQuery{
+		SpotifyID: track.SpotifyID,
+		Title:     track.Name,
+		Artist:    track.Artists,
+		Duration:  track.DurationMS / 1000,
+	})
+	if err != nil || match == nil {
+		return ""
+	}
+
+	path := libraryPathKey(match.Track.Path)
+	if !libraryUnderRoot(path, libraryPathKey(job.OutputDir)) || libraryUnderRoot(path, libraryPathKey(syncArchiveDir(job))) {
+		return ""
+	}
+	return match.Track.Path
+}
+
+func syncArchiveDir(job *SyncJob) string {
+	if job.ArchiveDir != "" {
+		return job.ArchiveDir
+	}
+	return filepath.Join(job.OutputDir, syncArchiveDirName)
+}
+
+func archiveSyncedFile(job *SyncJob, path string) (string, error) {
+	archiveDir := syncArchiveDir(job)
+
+	rel, err := filepath.Rel(job.OutputDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+
+	target := filepath.Join(archiveDir, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	ext := filepath.Ext(target)
+	base := strings.TrimSuffix(target, ext)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			break
+		}
+		target = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	if err := MoveLibraryFile(path, target); err != nil {
+		fmt.Printf("[Sync] Failed to update library for archived file: %v\n", err)
+	}
+	return target, nil
+}
+
+func RunSyncJob(ctx context.Context, id string, download SyncDownloadFunc) (*SyncReport, error) {
+	syncRunningLock.Lock()
+	if syncRunning[id] {
+		syncRunningLock.Unlock()
+		return nil, fmt.Errorf("sync job is already running")
+	}
+	syncRunning[id] = true
+	syncRunningLock.Unlock()
+
+	defer func() {
+		syncRunningLock.Lock()
+		delete(syncRunning, id)
+		syncRunningLock.Unlock()
+	}()
+
+	job, err := GetSyncJob(id)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &SyncReport{JobID: job.ID, StartedAt: time.Now().Unix()}
+
+	name, tracks, err := FetchSyncSource(ctx, job.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", job.SourceType, err)
+	}
+	report.SourceName = name
+	if job.Name == "" {
+		job.Name = name
+	}
+
+	fmt.Printf("[Sync] %s: %d tracks in %s\n", job.Name, len(tracks), job.SourceType)
+
+	current := make(map[string]SyncTrackState, len(tracks))
+	inSource := make(map[string]bool, len(tracks))
+	results := make([]SyncTrackResult, len(tracks))
+	var pending []int
+
+	for i, track := range tracks {
+		if track.SpotifyID == "" {
+			continue
+		}
+
+		results[i] = SyncTrackResult{
+			SpotifyID: track.SpotifyID,
+			Title:     track.Name,
+			Artist:    track.Artists,
+			Position:  i + 1,
+		}
+
+		if path := findSyncedFile(job, track); path != "" {
+			results[i].Status = SyncStatusExisting
+			results[i].Path = path
+		} else {
+			pending = append(pending, i)
+		}
+	}
+
+	if len(pending) > 0 {
+		downloads := make([]SyncDownload, 0, len(pending))
+		for _, i := range pending {
+			downloads = append(downloads, SyncDownload{Track: tracks[i], Position: i + 1})
+		}
+
+		itemIDs := download(downloads)
+		items := WaitForDownloadItems(ctx, itemIDs)
+		for n, i := range pending {
+			if n >= len(itemIDs) {
+				results[i].Status = SyncStatusFailed
+				results[i].Error = "download was not queued"
+				continue
+			}
+
+			item := items[itemIDs[n]]
+			switch {
+			case item.Status == StatusCompleted || item.Status == StatusSuspect:
+				results[i].Status = SyncStatusDownloaded
+				results[i].Path = item.FilePath
+			case item.Status == StatusSkipped && item.FilePath != "":
+				results[i].Status = SyncStatusExisting
+				results[i].Path = item.FilePath
+			default:
+				results[i].Status = SyncStatusFailed
+				results[i].Error = item.ErrorMessage
+			}
+		}
+	}
+
+	for _, result := range results {
+		if result.SpotifyID == "" {
+			continue
+		}
+
+		switch result.Status {
+		case SyncStatusDownloaded:
+			report.Downloaded++
+		case SyncStatusExisting:
+			report.Existing++
+		case SyncStatusFailed:
+			report.Failed++
+		}
+
+		inSource[result.SpotifyID] = true
+		report.Tracks = append(report.Tracks, result)
+
+		if result.Path != "" {
+			current[result.SpotifyID] = SyncTrackState{
+				SpotifyID: result.SpotifyID,
+				Title:     result.Title,
+				Artist:    result.Artist,
+				Position:  result.Position,
+				Path:      result.Path,
+			}
+		}
+	}
+	report.Total = len(report.Tracks)
+
+	for spotifyID, state := range job.Tracks {
+		if inSource[spotifyID] {
+			continue
+		}
+
+		result := SyncTrackResult{
+			SpotifyID: spotifyID,
+			Title:     state.Title,
+			Artist:    state.Artist,
+			Status:    SyncStatusRemoved,
+			Path:      state.Path,
+		}
+
+		if job.ArchiveRemoved && state.Path != "" {
+			if _, err := os.Stat(state.Path); err == nil {
+				archived, err := archiveSyncedFile(job, state.Path)
+				if err != nil {
+					result.Error = err.Error()
+				} else {
+					result.Status = SyncStatusArchived
+					result.Path = archived
+				}
+			}
+		}
+
+		if result.Status == SyncStatusArchived {
+			report.Archived++
+		} else {
+			report.Removed++
+		}
+		report.Tracks = append(report.Tracks, result)
+	}
+
+	if job.WritePlaylist {
+		files := make([]string, len(results))
+		for i, result := range results {
+			files[i] = result.Path
+		}
+
+		exported, err := ExportPlaylist(PlaylistExportRequest{
+			Name:      job.Name,
+			OutputDir: job.OutputDir,
+			Tracks:    tracks,
+			Files:     files,
+		})
+		if err != nil {
+			fmt.Printf("[Sync] Failed to write playlist: %v\n", err)
+		} else {
+			report.Playlist = exported.M3U8Path
+		}
+	}
+
+	report.FinishedAt = time.Now().Unix()
+	job.Tracks = current
+	job.LastRunAt = report.FinishedAt
+	job.LastReport = report
+
+	fmt.Printf("[Sync] %s: %d downloaded, %d existing, %d failed, %d archived, %d removed\n",
+		job.Name, report.Downloaded, report.Existing, report.Failed, report.Archived, report.Removed)
+
+	if err := SaveSyncJob(*job); err != nil {
+		return report, fmt.Errorf("failed to save sync job: %w", err)
+	}
+	return report, nil
+}
@@ -0,0 +1,618 @@
+package backend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	pathfilepath "path/filepath"
+	"strconv"
+	"strings"
+
+	id3v2 "github.com/bogem/id3v2/v2"
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+const musicBrainzUFIDOwner = "http://musicbrainz.org"
+
+type TagStore interface {
+	Read(filePath string) (Metadata, error)
+	Write(filePath string, metadata Metadata) error
+}
+
+func TagStoreFor(filePath string) (TagStore, error) {
+	ext := strings.ToLower(pathfilepath.Ext(filePath))
+	switch ext {
+	case ".flac":
+		return flacTagStore{}, nil
+	case ".mp3":
+		return mp3TagStore{}, nil
+	case ".m4a":
+		return m4aTagStore{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", ext)
+	}
+}
+
+func ReadTags(filePath string) (Metadata, error) {
+	store, err := TagStoreFor(filePath)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return store.Read(filePath)
+}
+
+func WriteTags(filePath string, metadata Metadata) error {
+	store, err := TagStoreFor(filePath)
+	if err != nil {
+		return err
+	}
+	return store.Write(filePath, metadata)
+}
+
+func applyTagField(m *Metadata, key, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+
+	switch strings.ToLower(key) {
+	case "title":
+		m.Title = value
+	case "artist":
+		m.Artist = value
+	case "album":
+		m.Album = value
+	case "albumartist", "album_artist", "album artist":
+		m.AlbumArtist = value
+	case "date", "year":
+		if m.Date == "" || len(value) > len(m.Date) {
+			m.Date = value
+		}
+	case "tracknumber", "track":
+		m.TrackNumber, m.TotalTracks = parseNumberPair(value, m.TotalTracks)
+	case "totaltracks", "tracktotal":
+		m.TotalTracks, _ = strconv.Atoi(value)
+	case "discnumber", "disc", "disk":
+		m.DiscNumber, m.TotalDiscs = parseNumberPair(value, m.TotalDiscs)
+	case "totaldiscs", "disctotal":
+		m.TotalDiscs, _ = strconv.Atoi(value)
+	case "genre":
+		m.Genre = value
+	case "composer":
+		m.Composer = value
+	case "bpm", "tmpo":
+		if bpm, err := strconv.ParseFloat(value, 64); err == nil {
+			m.BPM = int(bpm + 0.5)
+		}
+	case "isrc":
+		m.ISRC = value
+	case "url":
+		m.URL = value
+	case "spotify_trackid", "spotify track id":
+		m.SpotifyID = value
+	case "tidal_trackid", "tidal track id":
+		m.TidalTrackID = value
+	case "qobuz_trackid", "qobuz track id":
+		m.QobuzTrackID = value
+	case "publisher", "label", "organization":
+		m.Publisher = value
+	case "copyright":
+		m.Copyright = value
+	case "description", "comment":
+		if m.Description == "" {
+			m.Description = value
+		}
+	case "lyrics", "unsyncedlyrics", "lyrics-eng":
+		if m.Lyrics == "" {
+			m.Lyrics = value
+		}
+	case "musicbrainz_trackid", "musicbrainz track id":
+		m.MusicBrainzTrackID = value
+	case "musicbrainz_albumid", "musicbrainz album id":
+		m.MusicBrainzAlbumID = value
+	case "musicbrainz_artistid", "musicbrainz artist id":
+		m.MusicBrainzArtistID = value
+	case "musicbrainz_albumartistid", "musicbrainz album artist id":
+		m.MusicBrainzAlbumArtistID = value
+	case "musicbrainz_releasegroupid", "musicbrainz release group id":
+		m.MusicBrainzReleaseGroupID = value
+	case "replaygain_track_gain":
+		replayGainTags(m).TrackGain = parseReplayGainValue(value)
+	case "replaygain_track_peak":
+		replayGainTags(m).TrackPeak = parseReplayGainValue(value)
+	case "replaygain_album_gain":
+		rg := replayGainTags(m)
+		rg.AlbumGain = parseReplayGainValue(value)
+		rg.HasAlbum = true
+	case "replaygain_album_peak":
+		rg := replayGainTags(m)
+		rg.AlbumPeak = parseReplayGainValue(value)
+		rg.HasAlbum = true
+	}
+}
+
+func replayGainTags(m *Metadata) *ReplayGainTags {
+	if m.ReplayGain == nil {
+		m.ReplayGain = &ReplayGainTags{}
+	}
+	return m.ReplayGain
+}
+
+func parseReplayGainValue(value string) float64 {
+	value = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "dB"))
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
+
+func parseNumberPair(value string, total int) (int, int) {
+	parts := strings.SplitN(value, "/", 2)
+	num, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if len(parts) == 2 {
+		if t, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
+			total = t
+		}
+	}
+	return num, total
+}
+
+func formatNumberPair(num, total int) string {
+	if total > 0 {
+		return fmt.Sprintf("%d/%d", num, total)
+	}
+	return strconv.Itoa(num)
+}
+
+type tagField struct {
+	key   string
+	value string
+}
+
+func vorbisFields(m Metadata) []tagField {
+	fields := []tagField{
+		{flacvorbis.FIELD_TITLE, m.Title},
+		{flacvorbis.FIELD_ARTIST, m.Artist},
+		{flacvorbis.FIELD_ALBUM, m.Album},
+		{"ALBUMARTIST", m.AlbumArtist},
+		{flacvorbis.FIELD_DATE, m.Date},
+		{flacvorbis.FIELD_GENRE, m.Genre},
+		{"COMPOSER", m.Composer},
+		{"ISRC", m.ISRC},
+		{"URL", m.URL},
+		{"SPOTIFY_TRACKID", m.SpotifyID},
+		{"TIDAL_TRACKID", m.TidalTrackID},
+		{"QOBUZ_TRACKID", m.QobuzTrackID},
+		{"COPYRIGHT", m.Copyright},
+		{"PUBLISHER", m.Publisher},
+		{"DESCRIPTION", m.Description},
+		{"LYRICS", m.Lyrics},
+		{"MUSICBRAINZ_TRACKID", m.MusicBrainzTrackID},
+		{"MUSICBRAINZ_ALBUMID", m.MusicBrainzAlbumID},
+		{"MUSICBRAINZ_ARTISTID", m.MusicBrainzArtistID},
+		{"MUSICBRAINZ_ALBUMARTISTID", m.MusicBrainzAlbumArtistID},
+		{"MUSICBRAINZ_RELEASEGROUPID", m.MusicBrainzReleaseGroupID},
+	}
+
+	if m.TrackNumber > 0 {
+		fields = append(fields, tagField{flacvorbis.FIELD_TRACKNUMBER, strconv.Itoa(m.TrackNumber)})
+	}
+	if m.TotalTracks > 0 {
+		fields = append(fields, tagField{"TOTALTRACKS", strconv.Itoa(m.TotalTracks)})
+	}
+	if m.DiscNumber > 0 {
+		fields = append(fields, tagField{"DISCNUMBER", strconv.Itoa(m.DiscNumber)})
+	}
+	if m.TotalDiscs > 0 {
+		fields = append(fields, tagField{"TOTALDISCS", strconv.Itoa(m.TotalDiscs)})
+	}
+	if m.BPM > 0 {
+		fields = append(fields, tagField{"BPM", strconv.Itoa(m.BPM)})
+	}
+	if rg := m.ReplayGain; rg != nil {
+		fields = append(fields,
+			tagField{"REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", rg.TrackGain)},
+			tagField{"REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", rg.TrackPeak)},
+		)
+		if rg.HasAlbum {
+			fields = append(fields,
+				tagField{"REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", rg.AlbumGain)},
+				tagField{"REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", rg.AlbumPeak)},
+			)
+		}
+	}
+
+	return fields
+}
+
+func isCanonicalTagKey(key string) bool {
+	probe := Metadata{}
+	applyTagField(&probe, key, "1")
+	return probe != (Metadata{})
+}
+
+type flacTagStore struct{}
+
+func (flacTagStore) Read(filePath string) (Metadata, error) {
+	var metadata Metadata
+
+	f, err := flac.ParseFile(filePath)
+	if err != nil {
+		return metadata, fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	for _, block := range f.Meta {
+		if block.Type != flac.VorbisComment {
+			continue
+		}
+		cmt, err := flacvorbis.ParseFromMetaDataBlock(*block)
+		if err != nil {
+			continue
+		}
+		for _, comment := range cmt.Comments {
+			parts := strings.SplitN(comment, "=", 2)
+			if len(parts) == 2 {
+				applyTagField(&metadata, parts[0], parts[1])
+			}
+		}
+	}
+
+	return metadata, nil
+}
+
+func (flacTagStore) Write(filePath string, metadata Metadata) error {
+	f, err := flac.ParseFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to parse FLAC file: %w", err)
+	}
+
+	if err := setFLACComments(f, metadata); err != nil {
+		return err
+	}
+
+	if err := f.Save(filePath); err != nil {
+		return fmt.Errorf("failed to save FLAC file: %w", err)
+	}
+
+	return nil
+}
+
+func setFLACComments(f *flac.File, metadata Metadata) error {
+	var cmtIdx = -1
+	var existingCmt *flacvorbis.MetaDataBlockVorbisComment
+	for idx, block := range f.Meta {
+		if block.Type == flac.VorbisComment {
+			cmtIdx = idx
+			existingCmt, _ = flacvorbis.ParseFromMetaDataBlock(*block)
+			break
+		}
+	}
+
+	cmt := flacvorbis.New()
+	if existingCmt != nil {
+		cmt.Vendor = existingCmt.Vendor
+		for _, comment := range existingCmt.Comments {
+			parts := strings.SplitN(comment, "=", 2)
+			if len(parts) == 2 && !isCanonicalTagKey(parts[0]) {
+				_ = cmt.Add(parts[0], parts[1])
+			}
+		}
+	}
+
+	for _, field := range vorbisFields(metadata) {
+		if field.value != "" {
+			if err := cmt.Add(field.key, field.value); err != nil {
+				return fmt.Errorf("failed to add %s: %w", field.key, err)
+			}
+		}
+	}
+
+	cmtBlock := cmt.Marshal()
+	if cmtIdx < 0 {
+		f.Meta = append(f.Meta, &cmtBlock)
+	} else {
+		f.Meta[cmtIdx] = &cmtBlock
+	}
+
+	return nil
+}
+
+type id3LinkFrame struct {
+	URL string
+}
+
+func (lf id3LinkFrame) Size() int {
+	return len(lf.URL)
+}
+
+func (lf id3LinkFrame) UniqueIdentifier() string {
+	return lf.URL
+}
+
+func (lf id3LinkFrame) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, lf.URL)
+	return int64(n), err
+}
+
+var mp3TextFrames = []struct {
+	id  string
+	get func(m *Metadata) *string
+}{
+	{"TIT2", func(m *Metadata) *string { return &m.Title }},
+	{"TPE1", func(m *Metadata) *string { return &m.Artist }},
+	{"TALB", func(m *Metadata) *string { return &m.Album }},
+	{"TPE2", func(m *Metadata) *string { return &m.AlbumArtist }},
+	{"TCON", func(m *Metadata) *string { return &m.Genre }},
+	{"TCOM", func(m *Metadata) *string { return &m.Composer }},
+	{"TSRC", func(m *Metadata) *string { return &m.ISRC }},
+	{"TCOP", func(m *Metadata) *string { return &m.Copyright }},
+	{"TPUB", func(m *Metadata) *string { return &m.Publisher }},
+}
+
+var mp3UserTextFields = []struct {
+	description string
+	get         func(m *Metadata) *string
+}{
+	{"MusicBrainz Album Id", func(m *Metadata) *string { return &m.MusicBrainzAlbumID }},
+	{"MusicBrainz Artist Id", func(m *Metadata) *string { return &m.MusicBrainzArtistID }},
+	{"MusicBrainz Album Artist Id", func(m *Metadata) *string { return &m.MusicBrainzAlbumArtistID }},
+	{"MusicBrainz Release Group Id", func(m *Metadata) *string { return &m.MusicBrainzReleaseGroupID }},
+	{"Spotify Track Id", func(m *Metadata) *string { return &m.SpotifyID }},
+	{"Tidal Track Id", func(m *Metadata) *string { return &m.TidalTrackID }},
+	{"Qobuz Track Id", func(m *Metadata) *string { return &m.QobuzTrackID }},
+}
+
+type mp3TagStore struct{}
+
+func (mp3TagStore) Read(filePath string) (Metadata, error) {
+	var metadata Metadata
+
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return metadata, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	for _, field := range mp3TextFrames {
+		*field.get(&metadata) = tag.GetTextFrame(field.id).Text
+	}
+
+	for _, id := range []string{"TDRC", "TYER", "TDRL"} {
+		applyTagField(&metadata, "date", tag.GetTextFrame(id).Text)
+	}
+	applyTagField(&metadata, "track", tag.GetTextFrame("TRCK").Text)
+	applyTagField(&metadata, "disc", tag.GetTextFrame("TPOS").Text)
+	applyTagField(&metadata, "bpm", tag.GetTextFrame("TBPM").Text)
+
+	if frame, ok := tag.GetLastFrame("WOAF").(id3v2.UnknownFrame); ok {
+		metadata.URL = strings.TrimRight(string(frame.Body), "\x00")
+	}
+
+	for _, f := range tag.GetFrames("TXXX") {
+		if udtf, ok := f.(id3v2.UserDefinedTextFrame); ok {
+			applyTagField(&metadata, udtf.Description, udtf.Value)
+		}
+	}
+
+	for _, f := range tag.GetFrames("UFID") {
+		if ufid, ok := f.(id3v2.UFIDFrame); ok && ufid.OwnerIdentifier == musicBrainzUFIDOwner {
+			metadata.MusicBrainzTrackID = string(ufid.Identifier)
+		}
+	}
+
+	for _, f := range tag.GetFrames("COMM") {
+		if cf, ok := f.(id3v2.CommentFrame); ok && cf.Description == "" {
+			metadata.Description = cf.Text
+			break
+		}
+	}
+
+	for _, f := range tag.GetFrames("USLT") {
+		if uslt, ok := f.(id3v2.UnsynchronisedLyricsFrame); ok && uslt.Lyrics != "" {
+			metadata.Lyrics = uslt.Lyrics
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+func (mp3TagStore) Write(filePath string, metadata Metadata) error {
+	tag, err := id3v2.Open(filePath, id3v2.Options{Parse: true})
+	if err != nil {
+		return fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+	defer tag.Close()
+
+	setMP3Frames(tag, metadata)
+
+	if err := tag.Save(); err != nil {
+		return fmt.Errorf("failed to save MP3 tags: %w", err)
+	}
+
+	return nil
+}
+
+func setMP3Frames(tag *id3v2.Tag, metadata Metadata) {
+	setText := func(id, value string) {
+		if value == "" {
+			return
+		}
+		tag.DeleteFrames(id)
+		tag.AddTextFrame(id, tag.DefaultEncoding(), value)
+	}
+
+	for _, field := range mp3TextFrames {
+		setText(field.id, *field.get(&metadata))
+	}
+
+	if date := metadata.Date; date != "" {
+		for _, id := range []string{"TDRC", "TYER", "TDRL"} {
+			tag.DeleteFrames(id)
+		}
+		if tag.Version() == 3 && len(date) > 4 {
+			date = date[:4]
+		}
+		setText(tag.CommonID("Year"), date)
+	}
+
+	track, disc, bpm := "", "", ""
+	if metadata.TrackNumber > 0 {
+		track = formatNumberPair(metadata.TrackNumber, metadata.TotalTracks)
+	}
+	if metadata.DiscNumber > 0 {
+		disc = formatNumberPair(metadata.DiscNumber, metadata.TotalDiscs)
+	}
+	if metadata.BPM > 0 {
+		bpm = strconv.Itoa(metadata.BPM)
+	}
+	setText("TRCK", track)
+	setText("TPOS", disc)
+	setText("TBPM", bpm)
+
+	if metadata.URL != "" {
+		tag.DeleteFrames("WOAF")
+		tag.AddFrame("WOAF", id3LinkFrame{URL: metadata.URL})
+	}
+
+	var userFrames []id3v2.UserDefinedTextFrame
+	replaced := make(map[string]bool)
+	addUserText := func(description, value string) {
+		if value != "" {
+			replaced[strings.ToUpper(description)] = true
+			userFrames = append(userFrames, id3v2.UserDefinedTextFrame{
+				Encoding:    tag.DefaultEncoding(),
+				Description: description,
+				Value:       value,
+			})
+		}
+	}
+	for _, field := range mp3UserTextFields {
+		addUserText(field.description, *field.get(&metadata))
+	}
+	if rg := metadata.ReplayGain; rg != nil {
+		addUserText("REPLAYGAIN_TRACK_GAIN", fmt.Sprintf("%.2f dB", rg.TrackGain))
+		addUserText("REPLAYGAIN_TRACK_PEAK", fmt.Sprintf("%.6f", rg.TrackPeak))
+		if rg.HasAlbum {
+			addUserText("REPLAYGAIN_ALBUM_GAIN", fmt.Sprintf("%.2f dB", rg.AlbumGain))
+			addUserText("REPLAYGAIN_ALBUM_PEAK", fmt.Sprintf("%.6f", rg.AlbumPeak))
+		}
+	}
+	for _, f := range tag.GetFrames("TXXX") {
+		if udtf, ok := f.(id3v2.UserDefinedTextFrame); ok && !replaced[strings.ToUpper(udtf.Description)] {
+			userFrames = append(userFrames, udtf)
+		}
+	}
+	tag.DeleteFrames("TXXX")
+	for _, udtf := range userFrames {
+		tag.AddUserDefinedTextFrame(udtf)
+	}
+
+	if metadata.MusicBrainzTrackID != "" {
+		var ufids []id3v2.UFIDFrame
+		for _, f := range tag.GetFrames("UFID") {
+			if ufid, ok := f.(id3v2.UFIDFrame); ok && ufid.OwnerIdentifier != musicBrainzUFIDOwner {
+				ufids = append(ufids, ufid)
+			}
+		}
+		ufids = append(ufids, id3v2.UFIDFrame{
+			OwnerIdentifier: musicBrainzUFIDOwner,
+			Identifier:      []byte(metadata.MusicBrainzTrackID),
+		})
+		tag.DeleteFrames("UFID")
+		for _, ufid := range ufids {
+			tag.AddUFIDFrame(ufid)
+		}
+	}
+
+	if metadata.Description != "" {
+		var comments []id3v2.CommentFrame
+		for _, f := range tag.GetFrames("COMM") {
+			if cf, ok := f.(id3v2.CommentFrame); ok && cf.Description != "" {
+				comments = append(comments, cf)
+			}
+		}
+		comments = append(comments, id3v2.CommentFrame{
+			Encoding: tag.DefaultEncoding(),
+			Language: "eng",
+			Text:     metadata.Description,
+		})
+		tag.DeleteFrames("COMM")
+		for _, cf := range comments {
+			tag.AddCommentFrame(cf)
+		}
+	}
+
+	if metadata.Lyrics != "" {
+		tag.DeleteFrames(tag.CommonID("Unsynchronised lyrics/text transcription"))
+		tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+			Encoding: tag.DefaultEncoding(),
+			Language: "eng",
+			Lyrics:   metadata.Lyrics,
+		})
+	}
+}
+
+type m4aTagStore struct{}
+
+func (m4aTagStore) Read(filePath string) (Metadata, error) {
+	metadata, _, err := readMP4Tags(filePath)
+	return metadata, err
+}
+
+func (m4aTagStore) Write(filePath string, metadata Metadata) error {
+	return writeMP4Tags(filePath, metadata, nil)
+}
+
+func probeFormatTags(filePath string) (map[string]string, error) {
+	ffprobePath, err := GetFFprobePath()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ValidateExecutable(ffprobePath); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe executable: %w", err)
+	}
+
+	cmd := exec.Command(ffprobePath,
+		"-v", "quiet",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		filePath,
+	)
+
+	setHideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Format struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"format"`
+		Streams []struct {
+			Tags map[string]string `json:"tags"`
+		} `json:"streams"`
+	}
+
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, err
+	}
+
+	allTags := make(map[string]string)
+
+	for _, stream := range result.Streams {
+		for key, value := range stream.Tags {
+			allTags[strings.ToLower(key)] = value
+		}
+	}
+
+	for key, value := range result.Format.Tags {
+		allTags[strings.ToLower(key)] = value
+	}
+
+	return allTags, nil
+}
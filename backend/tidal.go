@@ -12,6 +12,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -349,42 +350,24 @@ func (t *TidalDownloader) DownloadAlbumArt(albumID string) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-func (t *TidalDownloader) DownloadFile(url, filepath string) error {
+func (t *TidalDownloader) DownloadFile(url, filepath, itemID string) error {
 
 	if strings.HasPrefix(url, "MANIFEST:") {
-		return t.DownloadFromManifest(strings.TrimPrefix(url, "MANIFEST:"), filepath)
+		return t.DownloadFromManifest(strings.TrimPrefix(url, "MANIFEST:"), filepath, itemID)
 	}
 
-	resp, err := t.client.Get(url)
-
-	if err != nil {
-		return fmt.Errorf("failed to download file: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
-	out, err := os.Create(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
-	}
-	defer out.Close()
-
-	pw := NewProgressWriter(out)
-	_, err = io.Copy(pw, resp.Body)
+	total, err := DownloadResumable(t.client, url, filepath, itemID)
 	if err != nil {
-		return fmt.Errorf("failed to write file: %w", err)
+		return err
 	}
 
-	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
+	fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(total)/(1024*1024))
 
 	fmt.Println("Download complete")
 	return nil
 }
 
-func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) error {
+func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath, itemID string) error {
 	directURL, initURL, mediaURLs, err := parseManifest(manifestB64)
 	if err != nil {
 		return fmt.Errorf("failed to parse manifest: %w", err)
@@ -397,29 +380,12 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 	if directURL != "" {
 		fmt.Println("Downloading file...")
 
-		resp, err := client.Get(directURL)
+		total, err := DownloadResumable(client, directURL, outputPath, itemID)
 		if err != nil {
-			return fmt.Errorf("failed to download file: %w", err)
+			return err
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode != 200 {
-			return fmt.Errorf("download failed with status %d", resp.StatusCode)
-		}
-
-		out, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
-		}
-		defer out.Close()
-
-		pw := NewProgressWriter(out)
-		_, err = io.Copy(pw, resp.Body)
-		if err != nil {
-			return fmt.Errorf("failed to write file: %w", err)
-		}
-
-		fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(pw.GetTotal())/(1024*1024))
+		fmt.Printf("\rDownloaded: %.2f MB (Complete)\n", float64(total)/(1024*1024))
 		fmt.Println("Download complete")
 		return nil
 	}
@@ -432,72 +398,14 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 		return fmt.Errorf("failed to create temp file: %w", err)
 	}
 
-	fmt.Print("Downloading init segment... ")
-	resp, err := client.Get(initURL)
-	if err != nil {
-		out.Close()
-		os.Remove(tempPath)
-		return fmt.Errorf("failed to download init segment: %w", err)
-	}
-	if resp.StatusCode != 200 {
-		resp.Body.Close()
-		out.Close()
-		os.Remove(tempPath)
-		return fmt.Errorf("init segment download failed with status %d", resp.StatusCode)
-	}
-	_, err = io.Copy(out, resp.Body)
-	resp.Body.Close()
+	fetcher := NewSegmentFetcher(client, itemID)
+	_, err = fetcher.FetchAll(append([]string{initURL}, mediaURLs...), out)
+	out.Close()
 	if err != nil {
-		out.Close()
 		os.Remove(tempPath)
-		return fmt.Errorf("failed to write init segment: %w", err)
-	}
-	fmt.Println("OK")
-
-	totalSegments := len(mediaURLs)
-	var totalBytes int64
-	lastTime := time.Now()
-	var lastBytes int64
-	for i, mediaURL := range mediaURLs {
-		resp, err := client.Get(mediaURL)
-		if err != nil {
-			out.Close()
-			os.Remove(tempPath)
-			return fmt.Errorf("failed to download segment %d: %w", i+1, err)
-		}
-		if resp.StatusCode != 200 {
-			resp.Body.Close()
-			out.Close()
-			os.Remove(tempPath)
-			return fmt.Errorf("segment %d download failed with status %d", i+1, resp.StatusCode)
-		}
-		n, err := io.Copy(out, resp.Body)
-		totalBytes += n
-		resp.Body.Close()
-		if err != nil {
-			out.Close()
-			os.Remove(tempPath)
-			return fmt.Errorf("failed to write segment %d: %w", i+1, err)
-		}
-
-		mbDownloaded := float64(totalBytes) / (1024 * 1024)
-		now := time.Now()
-		timeDiff := now.Sub(lastTime).Seconds()
-		var speedMBps float64
-		if timeDiff > 0.1 {
-			bytesDiff := float64(totalBytes - lastBytes)
-			speedMBps = (bytesDiff / (1024 * 1024)) / timeDiff
-			SetDownloadSpeed(speedMBps)
-			lastTime = now
-			lastBytes = totalBytes
-		}
-		SetDownloadProgress(mbDownloaded)
-
-		fmt.Printf("\rDownloading: %.2f MB (%d/%d segments)", mbDownloaded, i+1, totalSegments)
+		return fmt.Errorf("failed to download segments: %w", err)
 	}
 
-	out.Close()
-
 	tempInfo, _ := os.Stat(tempPath)
 	fmt.Printf("\rDownloaded: %.2f MB (Complete)          \n", float64(tempInfo.Size())/(1024*1024))
 
@@ -511,26 +419,33 @@ func (t *TidalDownloader) DownloadFromManifest(manifestB64, outputPath string) e
 		return fmt.Errorf("invalid ffmpeg executable: %w", err)
 	}
 
-	cmd := exec.Command(ffmpegPath, "-y", "-i", tempPath, "-vn", "-c:a", "flac", outputPath)
+	convertedPath := partFilePath(outputPath)
+	cmd := exec.Command(ffmpegPath, "-y", "-i", tempPath, "-vn", "-c:a", "flac", "-f", "flac", convertedPath)
 	setHideWindow(cmd)
 	var stderr strings.Builder
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
 
+		os.Remove(convertedPath)
 		m4aPath := strings.TrimSuffix(outputPath, ".flac") + ".m4a"
 		os.Rename(tempPath, m4aPath)
 		return fmt.Errorf("ffmpeg conversion failed (M4A saved as %s): %w - %s", m4aPath, err, stderr.String())
 	}
 
+	if err := os.Rename(convertedPath, outputPath); err != nil {
+		os.Remove(convertedPath)
+		return fmt.Errorf("failed to finalize file: %w", err)
+	}
+
 	os.Remove(tempPath)
 	fmt.Println("Download complete")
 
 	return nil
 }
 
-func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
+func (t *TidalDownloader) DownloadByURL(tidalURL string, req TrackRequest) (string, error) {
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
 			return "", fmt.Errorf("directory error: %w", err)
 		}
 	}
@@ -551,30 +466,21 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 		return "", fmt.Errorf("no track ID found")
 	}
 
-	artistName := spotifyArtistName
-	trackTitle := spotifyTrackName
-	albumTitle := spotifyAlbumName
-
-	artistNameForFile := sanitizeFilename(artistName)
-	trackTitleForFile := sanitizeFilename(trackTitle)
-	albumTitleForFile := sanitizeFilename(albumTitle)
-	albumArtistForFile := sanitizeFilename(spotifyAlbumArtist)
-
-	filename := buildTidalFilename(trackTitleForFile, artistNameForFile, albumTitleForFile, albumArtistForFile, spotifyReleaseDate, trackInfo.TrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	outputFilename := filepath.Join(outputDir, filename)
+	filename := buildTidalFilename(req, trackInfo.TrackNumber)
+	outputFilename := filepath.Join(req.OutputDir, filename)
 
 	if fileInfo, err := os.Stat(outputFilename); err == nil && fileInfo.Size() > 0 {
 		fmt.Printf("File already exists: %s (%.2f MB)\n", outputFilename, float64(fileInfo.Size())/(1024*1024))
 		return "EXISTS:" + outputFilename, nil
 	}
 
-	downloadURL, err := t.GetDownloadURL(trackInfo.ID, quality)
+	downloadURL, err := t.GetDownloadURL(trackInfo.ID, req.Quality)
 	if err != nil {
 		return "", err
 	}
 
 	fmt.Printf("Downloading to: %s\n", outputFilename)
-	if err := t.DownloadFile(downloadURL, outputFilename); err != nil {
+	if err := t.DownloadFile(downloadURL, outputFilename, req.ItemID); err != nil {
 		return "", err
 	}
 
@@ -582,10 +488,10 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = outputFilename + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -594,28 +500,12 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
-	if trackNumberToEmbed == 0 {
-		trackNumberToEmbed = 1
-	}
-
-	metadata := Metadata{
-		Title:       trackTitle,
-		Artist:      artistName,
-		Album:       albumTitle,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
-		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
-		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
-		Description: "https://github.com/afkarxyz/SpotiFLAC",
+	req.Tags.TidalID = strconv.FormatInt(trackInfo.ID, 10)
+	if !isValidISRC(req.Tags.ISRC) {
+		req.Tags.ISRC = trackInfo.ISRC
 	}
 
-	if err := EmbedMetadata(outputFilename, metadata, coverPath); err != nil {
+	if err := EmbedMetadata(outputFilename, req.Tags.ToMetadata(), coverPath); err != nil {
 		fmt.Printf("Tagging failed: %v\n", err)
 	} else {
 		fmt.Println("Metadata saved")
@@ -626,14 +516,14 @@ func (t *TidalDownloader) DownloadByURL(tidalURL, outputDir, quality, filenameFo
 	return outputFilename, nil
 }
 
-func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
+func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL string, req TrackRequest) (string, error) {
 	apis, err := t.GetAvailableAPIs()
 	if err != nil {
 		return "", fmt.Errorf("no APIs available for fallback: %w", err)
 	}
 
-	if outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
+	if req.OutputDir != "." {
+		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
 			return "", fmt.Errorf("directory error: %w", err)
 		}
 	}
@@ -654,31 +544,22 @@ func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality
 		return "", fmt.Errorf("no track ID found")
 	}
 
-	artistName := spotifyArtistName
-	trackTitle := spotifyTrackName
-	albumTitle := spotifyAlbumName
-
-	artistNameForFile := sanitizeFilename(artistName)
-	trackTitleForFile := sanitizeFilename(trackTitle)
-	albumTitleForFile := sanitizeFilename(albumTitle)
-	albumArtistForFile := sanitizeFilename(spotifyAlbumArtist)
-
-	filename := buildTidalFilename(trackTitleForFile, artistNameForFile, albumTitleForFile, albumArtistForFile, spotifyReleaseDate, trackInfo.TrackNumber, spotifyDiscNumber, filenameFormat, includeTrackNumber, position, useAlbumTrackNumber)
-	outputFilename := filepath.Join(outputDir, filename)
+	filename := buildTidalFilename(req, trackInfo.TrackNumber)
+	outputFilename := filepath.Join(req.OutputDir, filename)
 
 	if fileInfo, err := os.Stat(outputFilename); err == nil && fileInfo.Size() > 0 {
 		fmt.Printf("File already exists: %s (%.2f MB)\n", outputFilename, float64(fileInfo.Size())/(1024*1024))
 		return "EXISTS:" + outputFilename, nil
 	}
 
-	successAPI, downloadURL, err := getDownloadURLParallel(apis, trackInfo.ID, quality)
+	successAPI, downloadURL, err := getDownloadURLParallel(apis, trackInfo.ID, req.Quality)
 	if err != nil {
 		return "", err
 	}
 
 	fmt.Printf("Downloading to: %s\n", outputFilename)
 	downloader := NewTidalDownloader(successAPI)
-	if err := downloader.DownloadFile(downloadURL, outputFilename); err != nil {
+	if err := downloader.DownloadFile(downloadURL, outputFilename, req.ItemID); err != nil {
 		return "", err
 	}
 
@@ -686,10 +567,10 @@ func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality
 
 	coverPath := ""
 
-	if spotifyCoverURL != "" {
+	if req.CoverURL != "" {
 		coverPath = outputFilename + ".cover.jpg"
 		coverClient := NewCoverClient()
-		if err := coverClient.DownloadCoverToPath(spotifyCoverURL, coverPath, embedMaxQualityCover); err != nil {
+		if err := coverClient.DownloadCoverToPath(req.CoverURL, coverPath, req.EmbedMaxQualityCover); err != nil {
 			fmt.Printf("Warning: Failed to download Spotify cover: %v\n", err)
 			coverPath = ""
 		} else {
@@ -698,28 +579,12 @@ func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality
 		}
 	}
 
-	trackNumberToEmbed := spotifyTrackNumber
-	if trackNumberToEmbed == 0 {
-		trackNumberToEmbed = 1
-	}
-
-	metadata := Metadata{
-		Title:       trackTitle,
-		Artist:      artistName,
-		Album:       albumTitle,
-		AlbumArtist: spotifyAlbumArtist,
-		Date:        spotifyReleaseDate,
-		TrackNumber: trackNumberToEmbed,
-		TotalTracks: spotifyTotalTracks,
-		DiscNumber:  spotifyDiscNumber,
-		TotalDiscs:  spotifyTotalDiscs,
-		URL:         spotifyURL,
-		Copyright:   spotifyCopyright,
-		Publisher:   spotifyPublisher,
-		Description: "https://github.com/afkarxyz/SpotiFLAC",
+	req.Tags.TidalID = strconv.FormatInt(trackInfo.ID, 10)
+	if !isValidISRC(req.Tags.ISRC) {
+		req.Tags.ISRC = trackInfo.ISRC
 	}
 
-	if err := EmbedMetadata(outputFilename, metadata, coverPath); err != nil {
+	if err := EmbedMetadata(outputFilename, req.Tags.ToMetadata(), coverPath); err != nil {
 		fmt.Printf("Tagging failed: %v\n", err)
 	} else {
 		fmt.Println("Metadata saved")
@@ -730,14 +595,86 @@ func (t *TidalDownloader) DownloadByURLWithFallback(tidalURL, outputDir, quality
 	return outputFilename, nil
 }
 
-func (t *TidalDownloader) Download(spotifyTrackID, outputDir, quality, filenameFormat string, includeTrackNumber bool, position int, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate string, useAlbumTrackNumber bool, spotifyCoverURL string, embedMaxQualityCover bool, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks int, spotifyTotalDiscs int, spotifyCopyright, spotifyPublisher, spotifyURL string) (string, error) {
+func (t *TidalDownloader) Download(spotifyTrackID string, req TrackRequest) (string, error) {
 
 	tidalURL, err := t.GetTidalURLFromSpotify(spotifyTrackID)
 	if err != nil {
 		return "", fmt.Errorf("songlink couldn't find Tidal URL: %w", err)
 	}
 
-	return t.DownloadByURLWithFallback(tidalURL, outputDir, quality, filenameFormat, includeTrackNumber, position, spotifyTrackName, spotifyArtistName, spotifyAlbumName, spotifyAlbumArtist, spotifyReleaseDate, useAlbumTrackNumber, spotifyCoverURL, embedMaxQualityCover, spotifyTrackNumber, spotifyDiscNumber, spotifyTotalTracks, spotifyTotalDiscs, spotifyCopyright, spotifyPublisher, spotifyURL)
+	return t.DownloadByURLWithFallback(tidalURL, req)
+}
+
+func (t *TidalDownloader) Name() string {
+	return "tidal"
+}
+
+func (t *TidalDownloader) Capabilities() ProviderCapabilities {
+	return ProviderCapabilities{
+		ResolveBySpotifyID: true,
+		ResolveByURL:       true,
+		HiRes:              true,
+	}
+}
+
+func (t *TidalDownloader) Qualities() []string {
+	return []string{"LOSSLESS", "HI_RES_LOSSLESS"}
+}
+
+func (t *TidalDownloader) ResolveTrack(req ProviderRequest) (string, error) {
+	tidalURL := req.ServiceURL
+	if tidalURL == "" {
+		if req.Tags.SpotifyID == "" {
+			return "", fmt.Errorf("spotify ID is required for Tidal")
+		}
+
+		var err error
+		tidalURL, err = t.GetTidalURLFromSpotify(req.Tags.SpotifyID)
+		if err != nil {
+			return "", fmt.Errorf("songlink couldn't find Tidal URL: %w", err)
+		}
+	}
+
+	trackID, err := t.GetTrackIDFromURL(tidalURL)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d", trackID), nil
+}
+
+func (t *TidalDownloader) ProbeQuality(trackRef string) (string, error) {
+	trackID, err := strconv.ParseInt(trackRef, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid Tidal track ID: %s", trackRef)
+	}
+
+	trackInfo, err := t.GetTrackInfoByID(trackID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range trackInfo.MediaMetadata.Tags {
+		if tag == "HIRES_LOSSLESS" {
+			return "HI_RES_LOSSLESS", nil
+		}
+	}
+
+	if trackInfo.AudioQuality == "HI_RES" || trackInfo.AudioQuality == "HI_RES_LOSSLESS" {
+		return "HI_RES_LOSSLESS", nil
+	}
+	return trackInfo.AudioQuality, nil
+}
+
+func (t *TidalDownloader) FetchTrack(trackRef string, req ProviderRequest) (string, error) {
+	tidalURL := fmt.Sprintf("https://tidal.com/browse/track/%s", trackRef)
+
+	if req.ApiURL == "" || req.ApiURL == "auto" {
+		return t.DownloadByURLWithFallback(tidalURL, req.TrackRequest)
+	}
+
+	downloader := NewTidalDownloader(req.ApiURL)
+	return downloader.DownloadByURL(tidalURL, req.TrackRequest)
 }
 
 type SegmentTemplate struct {
@@ -995,11 +932,20 @@ func getDownloadURLParallel(apis []string, trackID int64, quality string) (strin
 	return "", "", fmt.Errorf("all %d APIs failed. Last error: %v", len(apis), lastError)
 }
 
-func buildTidalFilename(title, artist, album, albumArtist, releaseDate string, trackNumber, discNumber int, format string, includeTrackNumber bool, position int, useAlbumTrackNumber bool) string {
+func buildTidalFilename(req TrackRequest, trackNumber int) string {
 	var filename string
 
+	title := sanitizeFilename(req.Tags.Title)
+	artist := sanitizeFilename(req.Tags.Artist)
+	album := sanitizeFilename(req.Tags.Album)
+	albumArtist := sanitizeFilename(req.Tags.AlbumArtist)
+	releaseDate := req.Tags.ReleaseDate
+	discNumber := req.Tags.DiscNumber
+	format := req.FilenameFormat
+	position := req.Position
+
 	numberToUse := position
-	if useAlbumTrackNumber && trackNumber > 0 {
+	if req.UseAlbumTrackNumber && trackNumber > 0 {
 		numberToUse = trackNumber
 	}
 
@@ -1041,7 +987,7 @@ func buildTidalFilename(title, artist, album, albumArtist, releaseDate string, t
 			filename = fmt.Sprintf("%s - %s", title, artist)
 		}
 
-		if includeTrackNumber && position > 0 {
+		if req.IncludeTrackNumber && position > 0 {
 			filename = fmt.Sprintf("%02d. %s", numberToUse, filename)
 		}
 	}
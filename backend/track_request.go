@@ -0,0 +1,68 @@
+package backend
+
+import "strings"
+
+type TrackTags struct {
+	Title       string
+	Artist      string
+	Album       string
+	AlbumArtist string
+	ReleaseDate string
+	TrackNumber int
+	DiscNumber  int
+	TotalTracks int
+	TotalDiscs  int
+	Copyright   string
+	Publisher   string
+	SpotifyURL  string
+	SpotifyID   string
+	ISRC        string
+	TidalID     string
+	QobuzID     string
+}
+
+type TrackRequest struct {
+	OutputDir            string
+	Quality              string
+	FilenameFormat       string
+	IncludeTrackNumber   bool
+	Position             int
+	UseAlbumTrackNumber  bool
+	CoverURL             string
+	EmbedMaxQualityCover bool
+	ItemID               string
+	Tags                 TrackTags
+}
+
+func (t TrackTags) ToMetadata() Metadata {
+	trackNumber := t.TrackNumber
+	if trackNumber == 0 {
+		trackNumber = 1
+	}
+
+	isrc := strings.ToUpper(strings.TrimSpace(t.ISRC))
+	if !isValidISRC(isrc) {
+		isrc = ""
+	}
+
+	return Metadata{
+		Title:       t.Title,
+		Artist:      t.Artist,
+		Album:       t.Album,
+		AlbumArtist: t.AlbumArtist,
+		Date:        t.ReleaseDate,
+		TrackNumber: trackNumber,
+		TotalTracks: t.TotalTracks,
+		DiscNumber:  t.DiscNumber,
+		TotalDiscs:  t.TotalDiscs,
+		URL:         t.SpotifyURL,
+		ISRC:        isrc,
+		Copyright:   t.Copyright,
+		Publisher:   t.Publisher,
+		Description: "https://github.com/afkarxyz/SpotiFLAC",
+
+		SpotifyID:    t.SpotifyID,
+		TidalTrackID: t.TidalID,
+		QobuzTrackID: t.QobuzID,
+	}
+}
@@ -0,0 +1,90 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/md5"
+	"fmt"
+	"io"
+
+	"github.com/mewkiz/flac"
+)
+
+const durationToleranceMs = 3000
+
+type VerificationResult struct {
+	FilePath           string   `json:"file_path"`
+	Valid              bool     `json:"valid"`
+	Suspect            bool     `json:"suspect"`
+	Frames             int      `json:"frames"`
+	DecodedSamples     uint64   `json:"decoded_samples"`
+	DurationMs         int64    `json:"duration_ms"`
+	ExpectedDurationMs int64    `json:"expected_duration_ms,omitempty"`
+	MD5Checked         bool     `json:"md5_checked"`
+	Issues             []string `json:"issues,omitempty"`
+}
+
+func VerifyFLAC(filepath string, expectedDurationMs int64) (*VerificationResult, error) {
+	stream, err := flac.Open(filepath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC: %w", err)
+	}
+	defer stream.Close()
+
+	result := &VerificationResult{
+		FilePath:           filepath,
+		Valid:              true,
+		ExpectedDurationMs: expectedDurationMs,
+	}
+
+	info := stream.Info
+	md5sum := md5.New()
+
+	for {
+		frame, err := stream.ParseNext()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			result.Valid = false
+			result.Issues = append(result.Issues, fmt.Sprintf("decode failed at frame %d: %v", result.Frames, err))
+			break
+		}
+
+		frame.Hash(md5sum)
+		result.Frames++
+		if len(frame.Subframes) > 0 {
+			result.DecodedSamples += uint64(frame.Subframes[0].NSamples)
+		}
+	}
+
+	if info.SampleRate > 0 {
+		result.DurationMs = int64(result.DecodedSamples * 1000 / uint64(info.SampleRate))
+	}
+
+	if result.Valid && info.NSamples > 0 && result.DecodedSamples != info.NSamples {
+		result.Valid = false
+		result.Issues = append(result.Issues, fmt.Sprintf("decoded %d samples, STREAMINFO declares %d", result.DecodedSamples, info.NSamples))
+	}
+
+	var unset [md5.Size]byte
+	if result.Valid && !bytes.Equal(info.MD5sum[:], unset[:]) {
+		result.MD5Checked = true
+		if !bytes.Equal(md5sum.Sum(nil), info.MD5sum[:]) {
+			result.Valid = false
+			result.Issues = append(result.Issues, "decoded audio does not match STREAMINFO MD5 signature")
+		}
+	}
+
+	if expectedDurationMs > 0 {
+		diff := result.DurationMs - expectedDurationMs
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > durationToleranceMs {
+			result.Suspect = true
+			result.Issues = append(result.Issues, fmt.Sprintf("duration %dms differs from expected %dms", result.DurationMs, expectedDurationMs))
+		}
+	}
+
+	return result, nil
+}
@@ -31,9 +31,9 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
         if (paths.length === 0)
             return;
         const filePath = paths[0];
-        if (!filePath.toLowerCase().endsWith(".flac")) {
+        if (!/\.(flac|mp3|m4a|opus)$/i.test(filePath)) {
             toast.error("Invalid File Type", {
-                description: "Please drop a FLAC file for analysis",
+                description: "Please drop a FLAC, MP3, M4A or Opus file for analysis",
             });
             return;
         }
@@ -83,12 +83,12 @@ export function AudioAnalysisPage({ onBack }: AudioAnalysisPageProps) {
           </div>
           <p className="text-sm text-muted-foreground mb-4 text-center">
             {isDragging
-                ? "Drop your FLAC file here"
-                : "Drag and drop a FLAC file here, or click the button below to select"}
+                ? "Drop your audio file here"
+                : "Drag and drop an audio file here, or click the button below to select"}
           </p>
           <Button onClick={handleSelectFile} size="lg">
             <Upload className="h-5 w-5"/>
-            Select FLAC File
+            Select Audio File
           </Button>
         </div>)}
 
@@ -1,48 +1,19 @@
-import { useEffect, useState } from "react";
 import { X, Download, CheckCircle2, XCircle, Clock, FileCheck, Trash2, HardDrive, Zap, Timer } from "lucide-react";
 import { Button } from "@/components/ui/button";
 import { Dialog, DialogContent, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
 import { Badge } from "@/components/ui/badge";
-import { GetDownloadQueue, ClearCompletedDownloads, ClearAllDownloads } from "../../wailsjs/go/main/App";
+import { ClearCompletedDownloads, ClearAllDownloads } from "../../wailsjs/go/main/App";
+import { useDownloadQueueData } from "@/hooks/useDownloadQueueData";
 import { toastWithSound as toast } from "@/lib/toast-with-sound";
-import { backend } from "../../wailsjs/go/models";
 interface DownloadQueueProps {
     isOpen: boolean;
     onClose: () => void;
 }
 export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
-    const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
-        is_downloading: false,
-        queue: [],
-        current_speed: 0,
-        total_downloaded: 0,
-        session_start_time: 0,
-        queued_count: 0,
-        completed_count: 0,
-        failed_count: 0,
-        skipped_count: 0,
-    }));
-    useEffect(() => {
-        if (!isOpen)
-            return;
-        const fetchQueue = async () => {
-            try {
-                const info = await GetDownloadQueue();
-                setQueueInfo(info);
-            }
-            catch (error) {
-                console.error("Failed to get download queue:", error);
-            }
-        };
-        fetchQueue();
-        const interval = setInterval(fetchQueue, 500);
-        return () => clearInterval(interval);
-    }, [isOpen]);
+    const queueInfo = useDownloadQueueData(isOpen);
     const handleClearHistory = async () => {
         try {
             await ClearCompletedDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
         }
         catch (error) {
             console.error("Failed to clear history:", error);
@@ -51,8 +22,6 @@ export function DownloadQueue({ isOpen, onClose }: DownloadQueueProps) {
     const handleReset = async () => {
         try {
             await ClearAllDownloads();
-            const info = await GetDownloadQueue();
-            setQueueInfo(info);
             toast.success("Download queue reset");
         }
         catch (error) {
@@ -0,0 +1,83 @@
+import { useEffect, useState } from "react";
+import { Button } from "@/components/ui/button";
+import { Dialog, DialogContent, DialogDescription, DialogFooter, DialogHeader, DialogTitle, } from "@/components/ui/dialog";
+import { GetResumableDownloads, ResumeDownloads, DiscardResumableDownloads } from "../../wailsjs/go/main/App";
+import { toastWithSound as toast } from "@/lib/toast-with-sound";
+import { backend } from "../../wailsjs/go/models";
+export function ResumeDownloadsDialog() {
+    const [pending, setPending] = useState<backend.PersistedDownload[]>([]);
+    const [open, setOpen] = useState(false);
+    const [busy, setBusy] = useState(false);
+    useEffect(() => {
+        const loadPending = async () => {
+            try {
+                const items = await GetResumableDownloads();
+                if (items && items.length > 0) {
+                    setPending(items);
+                    setOpen(true);
+                }
+            }
+            catch (error) {
+                console.error("Failed to load unfinished downloads:", error);
+            }
+        };
+        loadPending();
+    }, []);
+    const failedCount = pending.filter((item) => item.status === "failed").length;
+    const handleResume = async () => {
+        setBusy(true);
+        try {
+            const count = await ResumeDownloads([]);
+            toast.success("Downloads resumed", { description: `${count} track(s) added back to the queue` });
+            setOpen(false);
+        }
+        catch (error) {
+            toast.error("Failed to resume downloads", { description: error instanceof Error ? error.message : String(error) });
+        }
+        finally {
+            setBusy(false);
+        }
+    };
+    const handleDiscard = async () => {
+        setBusy(true);
+        try {
+            await DiscardResumableDownloads([]);
+            setOpen(false);
+        }
+        catch (error) {
+            toast.error("Failed to discard downloads", { description: error instanceof Error ? error.message : String(error) });
+        }
+        finally {
+            setBusy(false);
+        }
+    };
+    return (<Dialog open={open} onOpenChange={setOpen}>
+            <DialogContent className="sm:max-w-[425px]">
+                <DialogHeader>
+                    <DialogTitle>Resume Downloads?</DialogTitle>
+                    <DialogDescription>
+                        {pending.length} download(s) from your last session did not finish
+                        {failedCount > 0 ? ` (${failedCount} failed)` : ""}. Do you want to add them back to the queue?
+                    </DialogDescription>
+                </DialogHeader>
+                <div className="max-h-48 overflow-y-auto space-y-1 text-sm">
+                    {pending.slice(0, 20).map((item) => (<div key={item.id} className="flex justify-between gap-2">
+                            <span className="truncate">{item.artist_name} - {item.track_name}</span>
+                            <span className="text-muted-foreground shrink-0">{item.status}</span>
+                        </div>))}
+                    {pending.length > 20 && (<div className="text-muted-foreground">and {pending.length - 20} more...</div>)}
+                </div>
+                <DialogFooter>
+                    <Button variant="outline" onClick={() => setOpen(false)} disabled={busy}>
+                        Later
+                    </Button>
+                    <Button variant="destructive" onClick={handleDiscard} disabled={busy}>
+                        Discard
+                    </Button>
+                    <Button onClick={handleResume} disabled={busy}>
+                        Resume
+                    </Button>
+                </DialogFooter>
+            </DialogContent>
+        </Dialog>);
+}
@@ -5,6 +5,7 @@ import { toastWithSound as toast } from "@/lib/toast-with-sound";
 import { joinPath, sanitizePath } from "@/lib/utils";
 import { logger } from "@/lib/logger";
 import type { TrackMetadata } from "@/types/api";
+import { backend } from "../../wailsjs/go/models";
 interface CheckFileExistenceRequest {
     spotify_id: string;
     track_name: string;
@@ -42,6 +43,25 @@ export function useDownload() {
         artists: string;
     } | null>(null);
     const shouldStopDownloadRef = useRef(false);
+    const writePlaylistFile = async (playlistName: string, outputDir: string, tracks: TrackMetadata[], files: string[]) => {
+        if (!files.some((file) => file)) {
+            return;
+        }
+        try {
+            const { ExportPlaylist } = await import("../../wailsjs/go/main/App");
+            const result = await ExportPlaylist(new backend.PlaylistExportRequest({
+                name: playlistName,
+                output_dir: outputDir,
+                tracks,
+                files,
+                formats: ["m3u8"],
+            }));
+            logger.info(`playlist written: ${result.m3u8_path} (${result.written} tracks, ${result.missing} missing)`);
+        }
+        catch (err) {
+            logger.error(`failed to write playlist: ${err}`);
+        }
+    };
     const downloadWithAutoFallback = async (isrc: string, settings: any, trackName?: string, artistName?: string, albumName?: string, playlistName?: string, position?: number, spotifyId?: string, durationMs?: number, releaseYear?: string, albumArtist?: string, releaseDate?: string, coverUrl?: string, spotifyTrackNumber?: number, spotifyDiscNumber?: number, spotifyTotalTracks?: number, spotifyTotalDiscs?: number, copyright?: string, publisher?: string) => {
         const service = settings.downloader;
         const query = trackName && artistName ? `${trackName} ${artistName} ` : undefined;
@@ -613,7 +633,7 @@ export function useDownload() {
             setDownloadingTrack(null);
         }
     };
-    const handleDownloadSelected = async (selectedTracks: string[], allTracks: TrackMetadata[], folderName?: string, isAlbum?: boolean) => {
+    const handleDownloadSelected = async (selectedTracks: string[], allTracks: TrackMetadata[], folderName?: string, isAlbum?: boolean, isPlaylist?: boolean) => {
         if (selectedTracks.length === 0) {
             toast.error("No tracks selected");
             return;
@@ -680,6 +700,7 @@ export function useDownload() {
             const trackID = track.spotify_id || track.isrc;
             return !existingSpotifyIDs.has(trackID);
         });
+        const playlistFiles = allTracks.map((track) => (track.isrc && existingFilePaths.get(track.spotify_id || track.isrc)) || "");
         let successCount = 0;
         let errorCount = 0;
         let skippedCount = existingSpotifyIDs.size;
@@ -700,6 +721,7 @@ export function useDownload() {
                 const releaseYear = track.release_date?.substring(0, 4);
                 const response = await downloadWithItemID(isrc, settings, itemID, track.name, track.artists, track.album_name, folderName, originalIndex + 1, track.spotify_id, track.duration_ms, isAlbum, releaseYear, track.album_artist || "", track.release_date, track.images, track.track_number, track.disc_number, track.total_tracks, track.total_discs, track.copyright, track.publisher);
                 if (response.success) {
+                    playlistFiles[allTracks.indexOf(track)] = response.file || "";
                     if (response.already_exists) {
                         skippedCount++;
                         logger.info(`skipped: ${track.name} - ${track.artists} (already exists)`);
@@ -741,6 +763,9 @@ export function useDownload() {
         shouldStopDownloadRef.current = false;
         const { CancelAllQueuedItems } = await import("../../wailsjs/go/main/App");
         await CancelAllQueuedItems();
+        if (isPlaylist && folderName) {
+            await writePlaylistFile(folderName, outputDir, allTracks, playlistFiles);
+        }
         logger.info(`batch complete: ${successCount} downloaded, ${skippedCount} skipped, ${errorCount} failed`);
         if (errorCount === 0 && skippedCount === 0) {
             toast.success(`Downloaded ${successCount} tracks successfully`);
@@ -761,7 +786,7 @@ export function useDownload() {
             toast.warning(parts.join(", "));
         }
     };
-    const handleDownloadAll = async (tracks: TrackMetadata[], folderName?: string, isAlbum?: boolean) => {
+    const handleDownloadAll = async (tracks: TrackMetadata[], folderName?: string, isAlbum?: boolean, isPlaylist?: boolean) => {
         const tracksWithIsrc = tracks.filter((track) => track.isrc);
         if (tracksWithIsrc.length === 0) {
             toast.error("No tracks available for download");
@@ -825,6 +850,7 @@ export function useDownload() {
             const trackID = track.spotify_id || track.isrc;
             return !existingSpotifyIDs.has(trackID);
         });
+        const playlistFiles = tracks.map((track) => (track.isrc && existingFilePaths.get(track.spotify_id || track.isrc)) || "");
         let successCount = 0;
         let errorCount = 0;
         let skippedCount = existingSpotifyIDs.size;
@@ -844,6 +870,7 @@ export function useDownload() {
                 const releaseYear = track.release_date?.substring(0, 4);
                 const response = await downloadWithItemID(track.isrc, settings, itemID, track.name, track.artists, track.album_name, folderName, originalIndex + 1, track.spotify_id, track.duration_ms, isAlbum, releaseYear, track.album_artist || "", track.release_date, track.images, track.track_number, track.disc_number, track.total_tracks, track.total_discs, track.copyright, track.publisher);
                 if (response.success) {
+                    playlistFiles[tracks.indexOf(track)] = response.file || "";
                     if (response.already_exists) {
                         skippedCount++;
                         logger.info(`skipped: ${track.name} - ${track.artists} (already exists)`);
@@ -883,6 +910,9 @@ export function useDownload() {
         shouldStopDownloadRef.current = false;
         const { CancelAllQueuedItems: CancelQueued } = await import("../../wailsjs/go/main/App");
         await CancelQueued();
+        if (isPlaylist && folderName) {
+            await writePlaylistFile(folderName, outputDir, tracks, playlistFiles);
+        }
         logger.info(`batch complete: ${successCount} downloaded, ${skippedCount} skipped, ${errorCount} failed`);
         if (errorCount === 0 && skippedCount === 0) {
             toast.success(`Downloaded ${successCount} tracks successfully`);
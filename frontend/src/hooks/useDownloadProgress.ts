@@ -1,19 +1,20 @@
-import { useState, useEffect, useRef } from "react";
+import { useState, useEffect } from "react";
 import { GetDownloadProgress } from "../../wailsjs/go/main/App";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
 export interface DownloadProgressInfo {
     is_downloading: boolean;
     mb_downloaded: number;
     speed_mbps: number;
 }
+const progressEvents = ["download:queue-changed", "download:item-started", "download:item-progress", "download:item-finished"];
 export function useDownloadProgress() {
     const [progress, setProgress] = useState<DownloadProgressInfo>({
         is_downloading: false,
         mb_downloaded: 0,
         speed_mbps: 0,
     });
-    const intervalRef = useRef<number | null>(null);
     useEffect(() => {
-        const pollProgress = async () => {
+        const fetchProgress = async () => {
             try {
                 const progressInfo = await GetDownloadProgress();
                 setProgress(progressInfo);
@@ -22,13 +23,9 @@ export function useDownloadProgress() {
                 console.error("Failed to get download progress:", error);
             }
         };
-        intervalRef.current = window.setInterval(pollProgress, 200);
-        pollProgress();
-        return () => {
-            if (intervalRef.current) {
-                clearInterval(intervalRef.current);
-            }
-        };
+        fetchProgress();
+        const unsubscribers = progressEvents.map((name) => EventsOn(name, fetchProgress));
+        return () => unsubscribers.forEach((unsubscribe) => unsubscribe());
     }, []);
     return progress;
 }
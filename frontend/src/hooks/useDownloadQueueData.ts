@@ -1,7 +1,14 @@
 import { useEffect, useState } from "react";
 import { GetDownloadQueue } from "../../wailsjs/go/main/App";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
 import { backend } from "../../wailsjs/go/models";
-export function useDownloadQueueData() {
+interface ItemProgressEvent {
+    id: string;
+    progress: number;
+    speed: number;
+}
+const queueRefreshEvents = ["download:queue-changed", "download:item-started", "download:item-finished"];
+export function useDownloadQueueData(enabled = true) {
     const [queueInfo, setQueueInfo] = useState<backend.DownloadQueueInfo>(new backend.DownloadQueueInfo({
         is_downloading: false,
         queue: [],
@@ -14,6 +21,8 @@ export function useDownloadQueueData() {
         skipped_count: 0,
     }));
     useEffect(() => {
+        if (!enabled)
+            return;
         const fetchQueue = async () => {
             try {
                 const info = await GetDownloadQueue();
@@ -23,9 +32,27 @@ export function useDownloadQueueData() {
                 console.error("Failed to get download queue:", error);
             }
         };
+        const applyProgress = (event: ItemProgressEvent) => {
+            setQueueInfo((prev) => {
+                let found = false;
+                const queue = prev.queue.map((item) => {
+                    if (item.id !== event.id)
+                        return item;
+                    found = true;
+                    return new backend.DownloadItem({ ...item, progress: event.progress, speed: event.speed });
+                });
+                if (!found)
+                    return prev;
+                const currentSpeed = queue
+                    .filter((item) => item.status === "downloading")
+                    .reduce((sum, item) => sum + item.speed, 0);
+                return new backend.DownloadQueueInfo({ ...prev, queue, current_speed: currentSpeed });
+            });
+        };
         fetchQueue();
-        const interval = setInterval(fetchQueue, 200);
-        return () => clearInterval(interval);
-    }, []);
+        const unsubscribers = queueRefreshEvents.map((name) => EventsOn(name, fetchQueue));
+        unsubscribers.push(EventsOn("download:item-progress", applyProgress));
+        return () => unsubscribers.forEach((unsubscribe) => unsubscribe());
+    }, [enabled]);
     return queueInfo;
 }
@@ -171,6 +171,7 @@ export interface AnalysisResult {
     total_samples: number;
     duration: number;
     bit_depth: string;
+    codec?: string;
     dynamic_range: number;
     peak_amplitude: number;
     rms_level: number;
@@ -271,4 +272,9 @@ export interface AudioMetadata {
     track_number: number;
     disc_number: number;
     year: string;
+    isrc?: string;
+    spotify_url?: string;
+    spotify_id?: string;
+    tidal_id?: string;
+    qobuz_id?: string;
 }
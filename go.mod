@@ -4,6 +4,7 @@ go 1.25.5
 
 require (
 	github.com/bogem/id3v2/v2 v2.1.4
+	github.com/fsnotify/fsnotify v1.9.0
 	github.com/go-flac/flacpicture v0.3.0
 	github.com/go-flac/flacvorbis v0.2.0
 	github.com/go-flac/go-flac v1.0.0